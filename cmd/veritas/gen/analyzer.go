@@ -22,6 +22,9 @@ var (
 	flagOutput  string
 	flagPackage string
 	flagInject  string
+	flagStrict  bool
+
+	flagAllowUncompilable bool
 )
 
 var Generator = &codegen.Generator{
@@ -34,11 +37,20 @@ func init() {
 	Generator.Flags.StringVar(&flagOutput, "o", "", "output file name")
 	Generator.Flags.StringVar(&flagPackage, "pkg", "validation", "package name")
 	Generator.Flags.StringVar(&flagInject, "inject", "", "inject code to file")
+	Generator.Flags.BoolVar(&flagStrict, "strict", false, "fail for rules that cannot be fully translated, instead of dropping them with a warning (recommended for new projects)")
+	Generator.Flags.BoolVar(&flagAllowUncompilable, "allow-uncompilable", false, "generate rules that do not type-check against the Go types, with a warning, instead of failing (ignored with -strict)")
 }
 
 func run(pass *codegen.Pass) error {
 	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil)) // Create a logger
-	p := parser.NewParser(logger)
+	var opts []parser.ParserOption
+	if flagStrict {
+		opts = append(opts, parser.WithStrict())
+	}
+	if flagAllowUncompilable {
+		opts = append(opts, parser.WithUncompilableRules())
+	}
+	p := parser.NewParser(logger, opts...)
 
 	// Create the PackageInfo struct from the pass
 	info := parser.PackageInfo{
@@ -46,6 +58,7 @@ func run(pass *codegen.Pass) error {
 		Syntax:    pass.Files,
 		TypesInfo: pass.TypesInfo,
 		Types:     pass.Pkg,
+		Fset:      pass.Fset,
 	}
 
 	// Call the new direct parsing function
@@ -97,11 +110,11 @@ func (g *GoCodeGenerator) Generate(pkgName string, ruleSets map[string]veritas.V
 	imports := map[string]string{
 		"veritas": "github.com/podhmo/veritas",
 	}
-	// Use a map to ensure package paths are unique
-	for _, t := range knownTypes {
-		if t.PackagePath != "" && t.PackageName != pkgName {
-			imports[t.PackageName] = t.PackagePath
-		}
+	// Use a map to ensure package paths are unique.
+	// The known types are formatted first, as their type arguments may refer to other packages.
+	knownTypeExprs := make([]string, len(knownTypes))
+	for i, t := range knownTypes {
+		knownTypeExprs[i] = t.Expr(pkgName, imports)
 	}
 
 	// 2. Print package and imports
@@ -120,41 +133,7 @@ func (g *GoCodeGenerator) Generate(pkgName string, ruleSets map[string]veritas.V
 
 	// 3. Print setupValidation function
 	fmt.Fprintf(&buf, "func setupValidation() {\n")
-	// Sort keys for deterministic output
-	keys := make([]string, 0, len(ruleSets))
-	for k := range ruleSets {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	for _, key := range keys {
-		ruleSet := ruleSets[key]
-		fmt.Fprintf(&buf, "\tveritas.Register(\"%s\", veritas.ValidationRuleSet{\n", key)
-		if len(ruleSet.TypeRules) > 0 {
-			fmt.Fprintf(&buf, "\t\tTypeRules: []string{\n")
-			for _, rule := range ruleSet.TypeRules {
-				fmt.Fprintf(&buf, "\t\t\t`%s`,\n", rule)
-			}
-			fmt.Fprintf(&buf, "\t\t},\n")
-		}
-		if len(ruleSet.FieldRules) > 0 {
-			fmt.Fprintf(&buf, "\t\tFieldRules: map[string][]string{\n")
-			fieldKeys := make([]string, 0, len(ruleSet.FieldRules))
-			for fk := range ruleSet.FieldRules {
-				fieldKeys = append(fieldKeys, fk)
-			}
-			sort.Strings(fieldKeys)
-			for _, fk := range fieldKeys {
-				fmt.Fprintf(&buf, "\t\t\t\"%s\": {\n", fk)
-				for _, rule := range ruleSet.FieldRules[fk] {
-					fmt.Fprintf(&buf, "\t\t\t\t`%s`,\n", rule)
-				}
-				fmt.Fprintf(&buf, "\t\t\t},\n")
-			}
-			fmt.Fprintf(&buf, "\t\t},\n")
-		}
-		fmt.Fprintf(&buf, "\t})\n")
-	}
+	injection.WriteRegistrations(&buf, ruleSets)
 	fmt.Fprintf(&buf, "}\n\n")
 
 	// 4. Print init function
@@ -163,12 +142,8 @@ func (g *GoCodeGenerator) Generate(pkgName string, ruleSets map[string]veritas.V
 	fmt.Fprintf(&buf, "// GetKnownTypes returns a list of all types that have validation rules.\n")
 	fmt.Fprintf(&buf, "func GetKnownTypes() []any {\n")
 	fmt.Fprintf(&buf, "\treturn []any{\n")
-	for _, t := range knownTypes {
-		if t.PackageName == pkgName {
-			fmt.Fprintf(&buf, "\t\t%s{},\n", t.TypeName)
-		} else {
-			fmt.Fprintf(&buf, "\t\t%s.%s{},\n", t.PackageName, t.TypeName)
-		}
+	for _, expr := range knownTypeExprs {
+		fmt.Fprintf(&buf, "\t\t%s{},\n", expr)
 	}
 	fmt.Fprintf(&buf, "\t}\n")
 	fmt.Fprintf(&buf, "}\n")
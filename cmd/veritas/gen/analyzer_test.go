@@ -2,6 +2,7 @@ package gen_test
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -26,6 +27,7 @@ func TestGenerator(t *testing.T) {
 		Args    []string
 		PkgPath string
 		Golden  string
+		WantErr string
 	}{
 		{
 			Name:    "default",
@@ -38,14 +40,63 @@ func TestGenerator(t *testing.T) {
 			PkgPath: "testpkg/a",
 			Golden:  "testdata/src/a/gogen.golden.pkg",
 		},
+		{
+			Name:    "strict",
+			Args:    []string{"-pkg=validation", "-strict"},
+			PkgPath: "testpkg/c",
+			WantErr: "c/c.go:5:14: Person: field Name: validate tag: column 1: required is not applicable to string",
+		},
+		{
+			Name:    "strict-typecheck",
+			Args:    []string{"-pkg=validation", "-strict"},
+			PkgPath: "testpkg/c",
+			WantErr: `c/c.go:3:1: Person: rule "self.Age < 200 || self.Nmae == \"root\"" does not compile: undefined field 'Nmae'`,
+		},
+		{
+			// Rules that do not type-check fail without -strict too.
+			Name:    "typecheck-by-default",
+			Args:    []string{"-pkg=validation"},
+			PkgPath: "testpkg/c",
+			WantErr: `c/c.go:3:1: Person: rule "self.Age < 200 || self.Nmae == \"root\"" does not compile: undefined field 'Nmae'`,
+		},
+		{
+			Name:    "not-strict",
+			Args:    []string{"-pkg=validation", "-strict=false", "-allow-uncompilable"},
+			PkgPath: "testpkg/c",
+			Golden:  "testdata/src/c/gogen.golden",
+		},
+		{
+			// Existing tags that strict mode rejects still generate, as before -strict was added;
+			// only the rules that do not type-check need -allow-uncompilable.
+			Name:    "not-strict-by-default",
+			Args:    []string{"-pkg=validation", "-allow-uncompilable"},
+			PkgPath: "testpkg/c",
+			Golden:  "testdata/src/c/gogen.golden",
+		},
+		{
+			Name:    "strict-ignores-allow-uncompilable",
+			Args:    []string{"-pkg=validation", "-strict", "-allow-uncompilable"},
+			PkgPath: "testpkg/c",
+			WantErr: `undefined field 'Nmae'`,
+		},
 	}
 
 	for _, c := range cases {
 		c := c
 		t.Run(c.Name, func(t *testing.T) {
+			// The flags are global, so reset those set by previous cases.
+			gen.Generator.Flags.VisitAll(func(f *flag.Flag) { f.Value.Set(f.DefValue) })
 			gen.Generator.Flags.Parse(c.Args)
 
 			dir := filepath.Join(codegentest.TestData(), "src")
+			if c.WantErr != "" {
+				rec := &errorRecorder{}
+				codegentest.Run(rec, dir, gen.Generator, c.PkgPath)
+				if got := strings.Join(rec.errs, "\n"); !strings.Contains(got, c.WantErr) {
+					t.Errorf("errors = %q, want containing %q", got, c.WantErr)
+				}
+				return
+			}
 			rs := codegentest.Run(t, dir, gen.Generator, c.PkgPath)
 
 			if len(rs) != 1 {
@@ -75,3 +126,12 @@ func TestGenerator(t *testing.T) {
 		})
 	}
 }
+
+// errorRecorder records the errors reported by codegentest.Run.
+type errorRecorder struct {
+	errs []string
+}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
@@ -180,9 +180,9 @@ func replaceOrAppendFunction(
 	return builder.String(), nil
 }
 
-func generateSetupValidation(w io.Writer, ruleSets map[string]veritas.ValidationRuleSet) error {
-	var buf bytes.Buffer
-	fmt.Fprintf(&buf, "func setupValidation() {\n")
+// WriteRegistrations writes the statements of setupValidation that register ruleSets, sorted
+// by key, for both the generated file and the injected function.
+func WriteRegistrations(w io.Writer, ruleSets map[string]veritas.ValidationRuleSet) {
 	keys := make([]string, 0, len(ruleSets))
 	for k := range ruleSets {
 		keys = append(keys, k)
@@ -191,32 +191,45 @@ func generateSetupValidation(w io.Writer, ruleSets map[string]veritas.Validation
 
 	for _, key := range keys {
 		ruleSet := ruleSets[key]
-		fmt.Fprintf(&buf, "\tveritas.Register(\"%s\", veritas.ValidationRuleSet{\n", key)
+		fmt.Fprintf(w, "\tveritas.Register(\"%s\", veritas.ValidationRuleSet{\n", key)
 		if len(ruleSet.TypeRules) > 0 {
-			fmt.Fprintf(&buf, "\t\tTypeRules: []string{\n")
+			fmt.Fprintf(w, "\t\tTypeRules: []string{\n")
 			for _, rule := range ruleSet.TypeRules {
-				fmt.Fprintf(&buf, "\t\t\t`%s`,\n", rule)
+				fmt.Fprintf(w, "\t\t\t`%s`,\n", rule)
 			}
-			fmt.Fprintf(&buf, "\t\t},\n")
+			fmt.Fprintf(w, "\t\t},\n")
 		}
-		if len(ruleSet.FieldRules) > 0 {
-			fmt.Fprintf(&buf, "\t\tFieldRules: map[string][]string{\n")
-			fieldKeys := make([]string, 0, len(ruleSet.FieldRules))
-			for fk := range ruleSet.FieldRules {
-				fieldKeys = append(fieldKeys, fk)
-			}
-			sort.Strings(fieldKeys)
-			for _, fk := range fieldKeys {
-				fmt.Fprintf(&buf, "\t\t\t\"%s\": {\n", fk)
-				for _, rule := range ruleSet.FieldRules[fk] {
-					fmt.Fprintf(&buf, "\t\t\t\t`%s`,\n", rule)
-				}
-				fmt.Fprintf(&buf, "\t\t\t},\n")
-			}
-			fmt.Fprintf(&buf, "\t\t},\n")
+		writeFieldRules(w, "FieldRules", ruleSet.FieldRules)
+		writeFieldRules(w, "CrossFieldRules", ruleSet.CrossFieldRules)
+		fmt.Fprintf(w, "\t})\n")
+	}
+}
+
+// writeFieldRules writes the field of a ValidationRuleSet named name, of rules keyed by field name.
+func writeFieldRules(w io.Writer, name string, rules map[string][]string) {
+	if len(rules) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\t\t%s: map[string][]string{\n", name)
+	fieldKeys := make([]string, 0, len(rules))
+	for fk := range rules {
+		fieldKeys = append(fieldKeys, fk)
+	}
+	sort.Strings(fieldKeys)
+	for _, fk := range fieldKeys {
+		fmt.Fprintf(w, "\t\t\t\"%s\": {\n", fk)
+		for _, rule := range rules[fk] {
+			fmt.Fprintf(w, "\t\t\t\t`%s`,\n", rule)
 		}
-		fmt.Fprintf(&buf, "\t})\n")
+		fmt.Fprintf(w, "\t\t\t},\n")
 	}
+	fmt.Fprintf(w, "\t\t},\n")
+}
+
+func generateSetupValidation(w io.Writer, ruleSets map[string]veritas.ValidationRuleSet) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "func setupValidation() {\n")
+	WriteRegistrations(&buf, ruleSets)
 	fmt.Fprintf(&buf, "}\n")
 
 	formatted, err := format.Source(buf.Bytes())
@@ -246,12 +259,9 @@ func generateGetKnownTypes(w io.Writer, pkgName string, knownTypes []parser_.Typ
 	fmt.Fprintf(&buf, "// GetKnownTypes returns a list of all types that have validation rules.\n")
 	fmt.Fprintf(&buf, "func GetKnownTypes() []any {\n")
 	fmt.Fprintf(&buf, "\treturn []any{\n")
+	// The imports are added by goimports.
 	for _, t := range knownTypes {
-		if t.PackageName == pkgName {
-			fmt.Fprintf(&buf, "\t\t%s{},\n", t.TypeName)
-		} else {
-			fmt.Fprintf(&buf, "\t\t%s.%s{},\n", t.PackageName, t.TypeName)
-		}
+		fmt.Fprintf(&buf, "\t\t%s{},\n", t.Expr(pkgName, nil))
 	}
 	fmt.Fprintf(&buf, "\t}\n")
 	fmt.Fprintf(&buf, "}\n")
@@ -6,3 +6,20 @@ type User struct {
 	Name  string `validate:"nonzero"`
 	Email string `validate:"nonzero,email"`
 }
+
+// Fields of type Nickname inherit its rules.
+// @cel: self.size() <= 16
+type Nickname string
+
+// Profile has an anonymous struct field, whose rules are registered as "a.Profile.Address".
+type Profile struct {
+	Address struct {
+		Zip string `validate:"nonzero"`
+	}
+}
+
+// Page is generic, so only its instantiations are listed as known types.
+// @veritas:instantiate Page[User]
+type Page[T any] struct {
+	Items []T `validate:"nonzero"`
+}
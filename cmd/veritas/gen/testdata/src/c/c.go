@@ -0,0 +1,7 @@
+package c
+
+// @cel: self.Age < 200 || self.Nmae == "root"
+type Person struct {
+	Name string `validate:"required"` // required only applies to pointers.
+	Age  int    `validate:"nonzero"`
+}
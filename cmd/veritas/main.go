@@ -2,30 +2,41 @@ package main
 
 import (
 	"flag"
+	"os"
+	"slices"
 
 	"github.com/gostaticanalysis/codegen/singlegenerator"
 	"github.com/podhmo/veritas/cmd/veritas/gen"
 	"github.com/podhmo/veritas/lint"
-	"github.com/podhmo/veritas/lint/required"
 	"golang.org/x/tools/go/analysis/multichecker"
 )
 
 func main() {
+	// The linter has flags of its own (e.g. -fix), so its arguments are parsed by multichecker.
+	if args, ok := lintArgs(os.Args); ok {
+		os.Args = args
+		multichecker.Main(lint.Analyzers...)
+		return
+	}
+
 	gen.Generator.Flags.VisitAll(func(f *flag.Flag) {
 		flag.CommandLine.Var(f.Value, f.Name, f.Usage)
 	})
-
-	var lintFlag bool
-	flag.BoolVar(&lintFlag, "lint", false, "run linter")
+	flag.Bool("lint", false, "run linter")
 	flag.Parse()
 
-	if lintFlag {
-		multichecker.Main(
-			lint.Analyzer,
-			required.Analyzer,
-		)
-		return
-	}
-
 	singlegenerator.Main(gen.Generator)
 }
+
+// lintArgs returns args without the -lint flag, and whether it was given.
+func lintArgs(args []string) ([]string, bool) {
+	for i, arg := range args {
+		switch arg {
+		case "--":
+			return args, false
+		case "-lint", "--lint", "-lint=true", "--lint=true":
+			return slices.Delete(slices.Clone(args), i, i+1), true
+		}
+	}
+	return args, false
+}
@@ -0,0 +1,280 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	celtypes "github.com/google/cel-go/common/types"
+	"github.com/podhmo/veritas"
+)
+
+// celChecker type-checks the generated rules against the Go types they apply to, so that mistakes
+// such as a misspelled field name are reported when the code is generated rather than at runtime.
+type celChecker struct {
+	env      *cel.Env
+	provider *typeProvider
+}
+
+func newCELChecker() (*celChecker, error) {
+	registry, err := celtypes.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	provider := &typeProvider{Provider: registry, structs: make(map[string]types.Type)}
+
+	// The same functions as the runtime environment of a default Engine.
+	opts := veritas.DefaultEnvOptions()
+	opts = append(opts, cel.HomogeneousAggregateLiterals(), cel.CustomTypeProvider(provider))
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &celChecker{env: env, provider: provider}, nil
+}
+
+// check type-checks rule, with 'self' being a value of type self.
+// Calls of undeclared functions are not reported, as they may be registered with veritas.NewEngine.
+func (c *celChecker) check(self types.Type, rule string) error {
+	env, err := c.env.Extend(cel.Variable("self", c.provider.celType(self)))
+	if err != nil {
+		return err
+	}
+	parsed, issues := env.Parse(rule)
+	if issues.Err() != nil {
+		return compileError(rule, issues.Errors(), nil)
+	}
+	if _, issues := env.Check(parsed); issues.Err() != nil {
+		return compileError(rule, issues.Errors(), parsed)
+	}
+	return nil
+}
+
+// compileError returns the error for the problems of rule, leaving out undeclared functions of parsed.
+// It returns nil if there are no other problems.
+func compileError(rule string, problems []*cel.Error, parsed *cel.Ast) error {
+	undeclared := make(map[string]bool)
+	for _, e := range problems {
+		if name, ok := undeclaredName(e.Message); ok {
+			undeclared[name] = true
+		}
+	}
+	functions := make(map[string]bool)
+	if parsed != nil {
+		celast.PreOrderVisit(parsed.NativeRep().Expr(), celast.NewExprVisitor(func(e celast.Expr) {
+			if e.Kind() != celast.CallKind {
+				return
+			}
+			call := e.AsCall()
+			if !undeclared[call.FunctionName()] {
+				return
+			}
+			functions[call.FunctionName()] = true
+			// The namespace of a qualified function, e.g. "my" in "my.fn(self)", is undeclared too.
+			if call.IsMemberFunction() && call.Target().Kind() == celast.IdentKind {
+				functions[call.Target().AsIdent()] = true
+			}
+		}))
+	}
+
+	var msgs []string
+	for _, e := range problems {
+		if name, ok := undeclaredName(e.Message); ok && functions[name] {
+			continue
+		}
+		msgs = append(msgs, e.Message)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("rule %q does not compile: %s", rule, strings.Join(msgs, "; "))
+}
+
+// undeclaredName returns the name in a checker message such as "undeclared reference to 'fn'".
+func undeclaredName(msg string) (string, bool) {
+	rest, ok := strings.CutPrefix(msg, "undeclared reference to '")
+	if !ok {
+		return "", false
+	}
+	name, _, ok := strings.Cut(rest, "'")
+	return name, ok
+}
+
+// typeProvider exposes Go struct types to the CEL type-checker, with the type mapping of
+// ext.NativeTypes, except that pointers are nullable. Structs are added by celType as they are
+// reached, and are named by their package path (e.g. "example.com/m/models.User").
+type typeProvider struct {
+	celtypes.Provider
+	structs map[string]types.Type
+}
+
+// celType returns the CEL type of values of the Go type t.
+func (p *typeProvider) celType(t types.Type) *celtypes.Type {
+	switch {
+	case isTime(t):
+		return celtypes.TimestampType
+	case isDuration(t):
+		return celtypes.DurationType
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return celtypes.StringType
+		case u.Info()&types.IsUnsigned != 0:
+			return celtypes.UintType
+		case u.Info()&types.IsInteger != 0:
+			return celtypes.IntType
+		case u.Info()&types.IsFloat != 0:
+			return celtypes.DoubleType
+		case u.Info()&types.IsBoolean != 0:
+			return celtypes.BoolType
+		}
+	case *types.Pointer:
+		elem := p.celType(u.Elem())
+		if elem.Kind() == celtypes.StructKind || elem.Kind() == celtypes.DynKind {
+			return elem // Already nullable.
+		}
+		return celtypes.NewNullableType(elem)
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			return celtypes.BytesType
+		}
+		return celtypes.NewListType(p.celType(u.Elem()))
+	case *types.Array:
+		return celtypes.NewListType(p.celType(u.Elem()))
+	case *types.Map:
+		return celtypes.NewMapType(p.celType(u.Key()), p.celType(u.Elem()))
+	case *types.Struct:
+		name := types.TypeString(t, (*types.Package).Path)
+		p.structs[name] = t
+		return celtypes.NewObjectType(name)
+	}
+	// Interfaces and type parameters can hold anything.
+	return celtypes.DynType
+}
+
+func (p *typeProvider) FindStructType(structType string) (*celtypes.Type, bool) {
+	if _, ok := p.structs[structType]; ok {
+		return celtypes.NewTypeTypeWithParam(celtypes.NewObjectType(structType)), true
+	}
+	return p.Provider.FindStructType(structType)
+}
+
+func (p *typeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	t, ok := p.structs[structType]
+	if !ok {
+		return p.Provider.FindStructFieldNames(structType)
+	}
+	var names []string
+	for name := range exportedFieldNames(t, make(map[types.Type]bool)) {
+		if _, ok := p.FindStructFieldType(structType, name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+func (p *typeProvider) FindStructFieldType(structType, fieldName string) (*celtypes.FieldType, bool) {
+	t, ok := p.structs[structType]
+	if !ok {
+		return p.Provider.FindStructFieldType(structType, fieldName)
+	}
+	// Only exported fields are visible, possibly promoted from embedded structs.
+	if !token.IsExported(fieldName) {
+		return nil, false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, fieldName)
+	field, ok := obj.(*types.Var)
+	if !ok || !field.IsField() {
+		return nil, false
+	}
+	return &celtypes.FieldType{Type: p.celType(field.Type())}, true
+}
+
+// exportedFieldNames returns the names of the exported fields of the struct t, including
+// those of embedded structs. Ambiguous names are included; callers look them up.
+func exportedFieldNames(t types.Type, seen map[types.Type]bool) map[string]bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	names := make(map[string]bool)
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if f.Exported() {
+			names[f.Name()] = true
+		}
+		if f.Embedded() {
+			for name := range exportedFieldNames(f.Type(), seen) {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
+
+func isDuration(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Duration"
+}
+
+func isByte(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Uint8
+}
+
+// CheckRule type-checks rule against self, the type of the value it applies to: the struct for
+// type and cross-field rules, the field's type for field rules. Rules generated from tags and
+// comments are checked the same way. Calls of undeclared functions are not reported.
+func (p *Parser) CheckRule(self types.Type, rule string) error {
+	if p.checker == nil {
+		checker, err := newCELChecker()
+		if err != nil {
+			return fmt.Errorf("failed to create the CEL environment for type-checking: %w", err)
+		}
+		p.checker = checker
+	}
+	return p.checker.check(self, rule)
+}
+
+// checkRules type-checks rules, applied to a value of type self. The problems are passed
+// through at, which adds their position, and handled by uncompilable.
+func (p *Parser) checkRules(self types.Type, rules []string, at func(error) error) error {
+	if self == nil {
+		return nil
+	}
+	var errs []error
+	for _, rule := range rules {
+		if err := p.CheckRule(self, rule); err != nil {
+			errs = append(errs, at(err))
+		}
+	}
+	return p.uncompilable(errors.Join(errs...))
+}
+
+// uncompilable handles err, rules that do not type-check. It is returned, unless the parser
+// was created WithUncompilableRules (and not WithStrict): then it is logged as a warning and nil
+// is returned, and the rules are kept, so that the Validator reports them if it cannot compile them either.
+func (p *Parser) uncompilable(err error) error {
+	if err == nil || p.strict || !p.allowUncompilable {
+		return err
+	}
+	p.logger.Warn("rule does not compile", "error", err)
+	return nil
+}
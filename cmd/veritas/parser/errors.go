@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"fmt"
+	"go/token"
+)
+
+// Error is a problem with the rules of a type, at the tag or comment it comes from
+// (or at the type's name, for problems with the type itself).
+type Error struct {
+	Pos      token.Pos
+	Position token.Position // Set if PackageInfo.Fset is set.
+	Err      error
+}
+
+func (e *Error) Error() string {
+	if !e.Position.IsValid() {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Position, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors returns the problems in err, an error returned by ParseDirectly.
+// Problems that have no position are returned as an *Error with an invalid Pos.
+func Errors(err error) []*Error {
+	if err == nil {
+		return nil
+	}
+	switch u := err.(type) {
+	case *Error:
+		return []*Error{u}
+	case interface{ Unwrap() []error }:
+		var errs []*Error
+		for _, err := range u.Unwrap() {
+			errs = append(errs, Errors(err)...)
+		}
+		return errs
+	default:
+		return []*Error{{Err: err}}
+	}
+}
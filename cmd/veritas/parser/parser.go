@@ -1,15 +1,22 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
 	"log/slog"
 	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/validatetag"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -20,6 +27,7 @@ type PackageInfo struct {
 	Syntax    []*ast.File
 	TypesInfo *types.Info
 	Types     *types.Package
+	Fset      *token.FileSet // Optional; errors are prefixed with the file:line:column of the field when set.
 }
 
 var shorthandCELMap = map[string]any{
@@ -29,7 +37,7 @@ var shorthandCELMap = map[string]any{
 	"nonzero": map[string]string{
 		"string": `self != ""`,
 		"int":    "self != 0",
-		"uint":   "self != 0",
+		"uint":   "self != 0u",
 		"float":  "self != 0.0",
 		"ptr":    "self != null",
 		"slice":  "self.size() > 0",
@@ -39,17 +47,117 @@ var shorthandCELMap = map[string]any{
 	"email": `self.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$')`,
 }
 
+// zeroCELMap holds CEL expressions testing whether a value is (or is not) the zero value
+// of its type category. Literals are typed, since they are also used in type-checked type rules.
+var zeroCELMap = map[string]struct{ zero, nonzero string }{
+	"string": {`self == ""`, `self != ""`},
+	"int":    {"self == 0", "self != 0"},
+	"uint":   {"self == 0u", "self != 0u"},
+	"float":  {"self == 0.0", "self != 0.0"},
+	"bool":   {"!self", "self"},
+	"ptr":    {"self == null", "self != null"},
+	"slice":  {"self.size() == 0", "self.size() > 0"},
+	"map":    {"self.size() == 0", "self.size() > 0"},
+}
+
+// conditionalOps lists the conditional requirement shorthands,
+// and whether they take a value after the field name (e.g. "required_if=Kind company").
+var conditionalOps = map[string]bool{
+	"required_if":      true,
+	"required_with":    false,
+	"required_without": false,
+	"excluded_unless":  true,
+}
+
+// crossFieldOps maps cross-field shorthands (e.g. "eqfield=PasswordConfirm") to CEL operators.
+// ordered reports whether the operator needs an ordered type (numbers, strings or time.Time).
+var crossFieldOps = map[string]struct {
+	op      string
+	ordered bool
+}{
+	"eqfield":  {"==", false},
+	"nefield":  {"!=", false},
+	"gtfield":  {">", true},
+	"gtefield": {">=", true},
+	"ltfield":  {"<", true},
+	"ltefield": {"<=", true},
+}
+
+// Shorthands returns the names of the shorthands that can be used in validate tags, sorted.
+func Shorthands() []string {
+	names := []string{"enum", "omitempty"}
+	for name := range shorthandCELMap {
+		names = append(names, name)
+	}
+	for name := range conditionalOps {
+		names = append(names, name)
+	}
+	for name := range crossFieldOps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsFieldReference reports whether the shorthand name refers to a sibling field by its first
+// argument, as in "eqfield=Other" and "required_if=Kind company".
+func IsFieldReference(name string) bool {
+	_, isCross := crossFieldOps[name]
+	_, isConditional := conditionalOps[name]
+	return isCross || isConditional
+}
+
+// ShorthandApplies reports whether the shorthand name, which takes no arguments
+// (e.g. "required" or "nonzero"), can be used on values of type t.
+func ShorthandApplies(name string, t types.Type) bool {
+	switch v := shorthandCELMap[name].(type) {
+	case string:
+		return true
+	case map[string]string:
+		_, ok := v[new(Parser).categorizeType(t)]
+		return ok
+	}
+	return false
+}
+
 type Parser struct {
-	logger *slog.Logger
+	logger            *slog.Logger
+	strict            bool
+	allowUncompilable bool
+	checker           *celChecker // Created on first use.
+}
+
+// ParserOption is an option for configuring a Parser.
+type ParserOption func(*Parser)
+
+// WithStrict makes the parser fail for rules that cannot be fully translated, such as a shorthand
+// that does not apply to the field's type, instead of dropping them with a warning.
+func WithStrict() ParserOption {
+	return func(p *Parser) {
+		p.strict = true
+	}
+}
+
+// WithUncompilableRules makes the parser keep rules that do not type-check against the Go types
+// they apply to, with a warning, instead of failing. The Validator then reports such a rule when
+// it evaluates it. Without this option, and with WithStrict, they are errors.
+func WithUncompilableRules() ParserOption {
+	return func(p *Parser) {
+		p.allowUncompilable = true
+	}
 }
 
-func NewParser(logger *slog.Logger) *Parser {
-	return &Parser{logger: logger}
+func NewParser(logger *slog.Logger, opts ...ParserOption) *Parser {
+	p := &Parser{logger: logger}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *Parser) Parse(path string) (map[string]veritas.ValidationRuleSet, []TypeInfo, error) {
 	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
 	}
 	pkgs, err := packages.Load(cfg, path)
 	if err != nil {
@@ -68,6 +176,7 @@ func (p *Parser) Parse(path string) (map[string]veritas.ValidationRuleSet, []Typ
 			Syntax:    pkg.Syntax,
 			TypesInfo: pkg.TypesInfo,
 			Types:     pkg.Types,
+			Fset:      pkg.Fset,
 		}
 		rules, types, err := p.ParseDirectly(info)
 		if err != nil {
@@ -95,6 +204,44 @@ type TypeInfo struct {
 	PackagePath string
 	PackageName string
 	TypeName    string
+	// TypeArgs are the type arguments of an instantiation of a generic type,
+	// requested with a `// @veritas:instantiate` directive.
+	TypeArgs []types.Type
+}
+
+// Expr returns the Go expression of the type in a file of package pkgName, such as
+// "sources.User" or "Box[string]". If imports is not nil, the packages that the expression
+// refers to are added to it, by name.
+func (t TypeInfo) Expr(pkgName string, imports map[string]string) string {
+	qualifier := func(pkg *types.Package) string {
+		if pkg.Name() == pkgName {
+			return ""
+		}
+		if imports != nil {
+			imports[pkg.Name()] = pkg.Path()
+		}
+		return pkg.Name()
+	}
+
+	var b strings.Builder
+	if t.PackageName != pkgName {
+		if imports != nil && t.PackagePath != "" {
+			imports[t.PackageName] = t.PackagePath
+		}
+		b.WriteString(t.PackageName + ".")
+	}
+	b.WriteString(t.TypeName)
+	if len(t.TypeArgs) > 0 {
+		b.WriteString("[")
+		for i, arg := range t.TypeArgs {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(types.TypeString(arg, qualifier))
+		}
+		b.WriteString("]")
+	}
+	return b.String()
 }
 
 // ParseDirectly parses validation rules from the given package information
@@ -102,6 +249,7 @@ type TypeInfo struct {
 func (p *Parser) ParseDirectly(info PackageInfo) (map[string]veritas.ValidationRuleSet, []TypeInfo, error) {
 	ruleSets := make(map[string]veritas.ValidationRuleSet)
 	var knownTypes []TypeInfo
+	var errs []error
 
 	for _, f := range info.Syntax {
 		ast.Inspect(f, func(n ast.Node) bool {
@@ -115,8 +263,25 @@ func (p *Parser) ParseDirectly(info PackageInfo) (map[string]veritas.ValidationR
 				if !ok {
 					continue
 				}
+				// In a grouped declaration, each spec has its own doc comment;
+				// the group's doc comment does not belong to any of them.
+				doc := typeSpec.Doc
+				if doc == nil && !genDecl.Lparen.IsValid() {
+					doc = genDecl.Doc
+				}
+
 				structType, ok := typeSpec.Type.(*ast.StructType)
 				if !ok {
+					rules, err := p.namedTypeRules(info, typeSpec, doc)
+					if err != nil {
+						errs = append(errs, err)
+					} else if len(rules) > 0 {
+						// Fields of this type inherit the rules at runtime, so it is not a known type.
+						ruleSets[fmt.Sprintf("%s.%s", info.PkgPath, typeSpec.Name.Name)] = veritas.ValidationRuleSet{
+							TypeRules:  rules,
+							FieldRules: make(map[string][]string),
+						}
+					}
 					continue
 				}
 
@@ -145,20 +310,37 @@ func (p *Parser) ParseDirectly(info PackageInfo) (map[string]veritas.ValidationR
 					FieldRules: make(map[string][]string),
 				}
 
-				if doc := genDecl.Doc; doc != nil {
-					for _, comment := range doc.List {
-						if strings.HasPrefix(comment.Text, "// @cel:") {
-							rule := strings.TrimSpace(strings.TrimPrefix(comment.Text, "// @cel:"))
-							ruleSet.TypeRules = append(ruleSet.TypeRules, rule)
-						}
+				ruleSet.TypeRules = append(ruleSet.TypeRules, celDirectives(doc)...)
+				var owner types.Type
+				if obj := info.TypesInfo.Defs[typeSpec.Name]; obj != nil {
+					owner = obj.Type()
+				}
+				fullTypeName := fmt.Sprintf("%s.%s", info.PkgPath, structName)
+				if err := p.checkTypeRules(info, typeSpec.Name.Name, owner, doc); err != nil {
+					errs = append(errs, err)
+				}
+				if err := p.extractRulesForStruct(info, owner, fullTypeName, structType, &ruleSet, ruleSets); err != nil {
+					errs = append(errs, err)
+				}
+
+				if typeSpec.TypeParams != nil && len(typeSpec.TypeParams.List) > 0 {
+					// A generic type cannot be listed in GetKnownTypes, only its instantiations can.
+					if hasRules(ruleSet) {
+						ruleSets[fullTypeName] = ruleSet
+					}
+					instances, err := p.instantiate(info, typeSpec, structType, doc, ruleSets)
+					if err != nil {
+						errs = append(errs, err)
 					}
+					if hasRules(ruleSet) && len(instances) == 0 {
+						p.logger.Warn("generic type has rules but no @veritas:instantiate directive, so it is not a known type", "type", fullTypeName)
+					}
+					knownTypes = append(knownTypes, instances...)
+					continue
 				}
-				p.extractRulesForStruct(info, structType, &ruleSet)
 
-				if len(ruleSet.TypeRules) > 0 || len(ruleSet.FieldRules) > 0 {
-					fullTypeName := fmt.Sprintf("%s.%s", info.PkgPath, structName)
+				if hasRules(ruleSet) {
 					ruleSets[fullTypeName] = ruleSet
-					// NOTE: typeSpec.Name.Name does not include generic parameters.
 					knownTypes = append(knownTypes, TypeInfo{
 						PackagePath: info.PkgPath,
 						PackageName: info.Types.Name(),
@@ -170,66 +352,797 @@ func (p *Parser) ParseDirectly(info PackageInfo) (map[string]veritas.ValidationR
 		})
 	}
 
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
 	return ruleSets, knownTypes, nil
 }
 
-func (p *Parser) extractRulesForStruct(info PackageInfo, structType *ast.StructType, ruleSet *veritas.ValidationRuleSet) {
-	for _, field := range structType.Fields.List {
-		// Embedded field
-		if field.Names == nil {
-			if embeddedStruct, ok := p.getEmbeddedStruct(info, field.Type); ok {
-				p.extractRulesForStruct(info, embeddedStruct, ruleSet)
-			}
+func hasRules(ruleSet veritas.ValidationRuleSet) bool {
+	return len(ruleSet.TypeRules) > 0 || len(ruleSet.FieldRules) > 0 || len(ruleSet.CrossFieldRules) > 0
+}
+
+// instantiate collects the rules of the instantiations of a generic struct type that are
+// requested with `// @veritas:instantiate Box[string]` lines in its doc comment.
+// Shorthands are expanded for the instantiated field types, and each rule set is keyed by the
+// instantiation's name as reported by reflect (e.g. "pkg.Box[string]"), so that the Validator
+// prefers it to the generic rule set. The instantiations with rules are returned as known types.
+func (p *Parser) instantiate(info PackageInfo, typeSpec *ast.TypeSpec, structType *ast.StructType, doc *ast.CommentGroup, ruleSets map[string]veritas.ValidationRuleSet) ([]TypeInfo, error) {
+	obj := info.TypesInfo.Defs[typeSpec.Name]
+	if obj == nil {
+		return nil, nil
+	}
+	origin, _ := obj.Type().(*types.Named)
+
+	var known []TypeInfo
+	var errs []error
+	for _, expr := range instantiateDirectives(doc) {
+		// The position of the declaration puts the file's imports in scope.
+		tv, err := types.Eval(token.NewFileSet(), info.Types, typeSpec.Name.Pos(), expr)
+		if err != nil {
+			errs = append(errs, p.positionError(info, typeSpec.Name.Pos(), fmt.Errorf("%s: invalid @veritas:instantiate %s: %w", typeSpec.Name.Name, expr, err)))
+			continue
+		}
+		inst, ok := tv.Type.(*types.Named)
+		if !tv.IsType() || !ok || inst.Origin() != origin || inst.TypeArgs().Len() == 0 {
+			errs = append(errs, p.positionError(info, typeSpec.Name.Pos(), fmt.Errorf("%s: @veritas:instantiate %s is not an instantiation of %s", typeSpec.Name.Name, expr, typeSpec.Name.Name)))
 			continue
 		}
 
-		fieldName := field.Names[0].Name
-		if field.Tag == nil {
+		// The fields of the instance are in the order of the declaration, with the type arguments substituted.
+		fields := p.astFields(info, structType)
+		st := inst.Underlying().(*types.Struct)
+		for i := range fields {
+			fields[i].typ = st.Field(i).Type()
+		}
+
+		key := fmt.Sprintf("%s.%s", info.PkgPath, reflectName(inst))
+		if err := p.checkTypeRules(info, expr, inst, doc); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ruleSet := veritas.ValidationRuleSet{
+			TypeRules:  celDirectives(doc),
+			FieldRules: make(map[string][]string),
+		}
+		if err := p.extractRulesForFields(info, inst, key, fields, &ruleSet, ruleSets, map[*types.Named]bool{inst: true}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !hasRules(ruleSet) {
 			continue
 		}
-		tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
-		validateTag, ok := tag.Lookup("validate")
+		ruleSets[key] = ruleSet
+
+		args := make([]types.Type, inst.TypeArgs().Len())
+		for i := range args {
+			args[i] = inst.TypeArgs().At(i)
+		}
+		known = append(known, TypeInfo{
+			PackagePath: info.PkgPath,
+			PackageName: info.Types.Name(),
+			TypeName:    typeSpec.Name.Name,
+			TypeArgs:    args,
+		})
+	}
+	return known, errors.Join(errs...)
+}
+
+// instantiateDirectives returns the type expressions of the `// @veritas:instantiate` lines of doc.
+func instantiateDirectives(doc *ast.CommentGroup) []string {
+	if doc == nil {
+		return nil
+	}
+	var exprs []string
+	for _, comment := range doc.List {
+		text, ok := strings.CutPrefix(comment.Text, "//")
 		if !ok {
 			continue
 		}
+		if expr, ok := strings.CutPrefix(strings.TrimSpace(text), "@veritas:instantiate "); ok {
+			exprs = append(exprs, strings.TrimSpace(expr))
+		}
+	}
+	return exprs
+}
 
-		tv := info.TypesInfo.TypeOf(field.Type)
+// reflectName returns the name of an instantiated type as reported by reflect.Type.Name,
+// e.g. "Box[*example.com/p.Item]".
+func reflectName(inst *types.Named) string {
+	args := make([]string, inst.TypeArgs().Len())
+	for i := range args {
+		arg := inst.TypeArgs().At(i)
+		if iface, ok := arg.Underlying().(*types.Interface); ok && iface.Empty() && !isNamed(arg) {
+			args[i] = "interface {}" // reflect's spelling of any.
+			continue
+		}
+		args[i] = types.TypeString(arg, func(pkg *types.Package) string { return pkg.Path() })
+	}
+	return inst.Obj().Name() + "[" + strings.Join(args, ",") + "]"
+}
+
+func isNamed(t types.Type) bool {
+	_, ok := t.(*types.Named)
+	return ok
+}
+
+// namedTypeRules returns the `// @cel:` rules of a named non-struct type, such as `type Email string`.
+// Only scalar, slice and map types can have rules; 'self' is the value of the type.
+func (p *Parser) namedTypeRules(info PackageInfo, typeSpec *ast.TypeSpec, doc *ast.CommentGroup) ([]string, error) {
+	rules := celDirectives(doc)
+	isEnum := hasDirective(doc, "@veritas:enum")
+	if len(rules) == 0 && !isEnum {
+		return nil, nil
+	}
+	if typeSpec.Assign.IsValid() || typeSpec.TypeParams != nil {
+		return nil, p.positionError(info, typeSpec.Name.Pos(), fmt.Errorf("%s: rules are not supported on aliases and generic types", typeSpec.Name.Name))
+	}
+	obj := info.TypesInfo.Defs[typeSpec.Name]
+	if obj == nil {
+		return nil, nil
+	}
+	switch obj.Type().Underlying().(type) {
+	case *types.Basic, *types.Slice, *types.Map:
+	default:
+		return nil, p.positionError(info, typeSpec.Name.Pos(), fmt.Errorf("%s: rules are only supported on struct, scalar, slice and map types", typeSpec.Name.Name))
+	}
+
+	if err := p.checkTypeRules(info, typeSpec.Name.Name, obj.Type(), doc); err != nil {
+		return nil, err
+	}
+
+	if isEnum {
+		rule, err := p.enumCEL(obj.Type(), "self")
+		if err != nil {
+			return nil, p.positionError(info, typeSpec.Name.Pos(), fmt.Errorf("%s: @veritas:enum: %w", typeSpec.Name.Name, err))
+		}
+		rules = append([]string{rule}, rules...)
+	}
+	return rules, nil
+}
+
+// hasDirective reports whether doc has a line consisting of the directive, e.g. "// @veritas:enum".
+func hasDirective(doc *ast.CommentGroup, directive string) bool {
+	if doc == nil {
+		return false
+	}
+	for _, comment := range doc.List {
+		if text, ok := strings.CutPrefix(comment.Text, "//"); ok && strings.TrimSpace(text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// enumCEL returns a rule checking that varName is one of the constants declared with type t,
+// e.g. `self in ["active", "inactive"]`. The constants are listed in declaration order.
+func (p *Parser) enumCEL(t types.Type, varName string) (string, error) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return "", fmt.Errorf("%s is not a named type", t)
+	}
+	if _, ok := named.Underlying().(*types.Basic); !ok {
+		return "", fmt.Errorf("%s is not a scalar type", t)
+	}
+
+	// Constants of other packages are only visible if they are exported.
+	pkg := named.Obj().Pkg()
+	scope := pkg.Scope()
+	var consts []*types.Const
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if ok && types.Identical(c.Type(), named) {
+			consts = append(consts, c)
+		}
+	}
+	if len(consts) == 0 {
+		return "", fmt.Errorf("no constants of type %s are declared in %s", t, pkg.Path())
+	}
+	sort.SliceStable(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+
+	seen := make(map[string]bool)
+	var values []string
+	for _, c := range consts {
+		lit, err := p.constLiteral(c.Val(), named)
+		if err != nil {
+			return "", fmt.Errorf("constant %s: %w", c.Name(), err)
+		}
+		if !seen[lit] {
+			seen[lit] = true
+			values = append(values, lit)
+		}
+	}
+	return fmt.Sprintf("%s in [%s]", varName, strings.Join(values, ", ")), nil
+}
+
+// constLiteral converts the value of a constant of type t into a CEL literal.
+func (p *Parser) constLiteral(val constant.Value, t types.Type) (string, error) {
+	switch p.categorizeType(t) {
+	case "string":
+		return strconv.Quote(constant.StringVal(val)), nil
+	case "int":
+		return val.ExactString(), nil
+	case "uint":
+		return val.ExactString() + "u", nil
+	case "float":
+		f, _ := constant.Float64Val(val)
+		return p.celLiteral(strconv.FormatFloat(f, 'g', -1, 64), t)
+	case "bool":
+		return strconv.FormatBool(constant.BoolVal(val)), nil
+	default:
+		return "", fmt.Errorf("unsupported constant type %s", t)
+	}
+}
+
+// celDirectives returns the rules of the `// @cel:` directives in doc.
+// A long rule can continue on the following lines, indented by at least two spaces or a tab.
+// Blank lines between them are skipped, as gofmt adds one before the indented lines of a doc comment:
+//
+//	// @cel: self.StartAt < self.EndAt &&
+//	//
+//	//	self.EndAt - self.StartAt <= duration('24h')
+func celDirectives(doc *ast.CommentGroup) []string {
+	var rules []string
+	for _, c := range CELComments(doc) {
+		rules = append(rules, c.Rule)
+	}
+	return rules
+}
+
+// CELComment is a rule written as a `// @cel:` directive, with the position of the directive.
+type CELComment struct {
+	Rule string
+	Pos  token.Pos
+}
+
+// CELComments is like celDirectives, but keeps the position of each rule.
+func CELComments(doc *ast.CommentGroup) []CELComment {
+	if doc == nil {
+		return nil
+	}
+	var rules []CELComment
+	inRule := false
+	for _, comment := range doc.List {
+		text, ok := strings.CutPrefix(comment.Text, "//")
+		if !ok {
+			inRule = false
+			continue
+		}
+		if rule, ok := strings.CutPrefix(strings.TrimSpace(text), "@cel:"); ok {
+			rules = append(rules, CELComment{Rule: strings.TrimSpace(rule), Pos: comment.Pos()})
+			inRule = true
+			continue
+		}
+		// gofmt turns the continuation in the doc comment of a declaration into a code block,
+		// which is preceded by a blank line, so blank lines do not end a rule.
+		if inRule && strings.TrimSpace(text) == "" {
+			continue
+		}
+		if inRule && (strings.HasPrefix(text, "  ") || strings.HasPrefix(text, "\t") || strings.HasPrefix(text, " \t")) {
+			rules[len(rules)-1].Rule += " " + strings.TrimSpace(text)
+			continue
+		}
+		inRule = false
+	}
+	return rules
+}
+
+// structField is a field of a struct whose rules are being collected.
+// It is built from the syntax for structs of the package being parsed, and from
+// type information (tags only) for structs embedded from other packages.
+type structField struct {
+	name         string
+	pos          token.Pos // The position of the tag, or of the field if it has no tag.
+	typ          types.Type
+	expr         ast.Expr // The type expression, if the field is read from the syntax.
+	embedded     bool
+	tag          string       // The raw struct tag.
+	commentRules []CELComment // Rules written as @cel comments on the field.
+}
+
+// extractRulesForStruct collects the rules of structType's fields into ruleSet.
+// owner is the type that declares the rules; cross-field references are resolved against it.
+// key is the rule-set key of owner. The rule sets of anonymous struct fields are keyed
+// by the field's path from it (e.g. "pkg.User.Address") and added to nested.
+func (p *Parser) extractRulesForStruct(info PackageInfo, owner types.Type, key string, structType *ast.StructType, ruleSet *veritas.ValidationRuleSet, nested map[string]veritas.ValidationRuleSet) error {
+	seen := map[*types.Named]bool{}
+	if named, ok := owner.(*types.Named); ok {
+		seen[named] = true
+	}
+	return p.extractRulesForFields(info, owner, key, p.astFields(info, structType), ruleSet, nested, seen)
+}
+
+// extractRulesForFields collects the rules of fields into ruleSet. The fields of embedded
+// structs are promoted, so their rules are collected under their own names.
+// seen holds the embedded types already visited, to stop at recursive embeddings.
+func (p *Parser) extractRulesForFields(info PackageInfo, owner types.Type, key string, fields []structField, ruleSet *veritas.ValidationRuleSet, nested map[string]veritas.ValidationRuleSet, seen map[*types.Named]bool) error {
+	var errs []error
+	for _, field := range fields {
+		if field.embedded {
+			embedded, ok := p.embeddedFields(info, field.typ, seen)
+			if !ok {
+				continue
+			}
+			if err := p.extractRulesForFields(info, owner, key, embedded, ruleSet, nested, seen); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		fieldName := field.name
+		if err := p.extractAnonymousStruct(info, key+"."+fieldName, field, nested); err != nil {
+			errs = append(errs, err)
+		}
+		validateTag, hasTag := reflect.StructTag(field.tag).Lookup("validate")
+		if !hasTag && len(field.commentRules) == 0 {
+			continue
+		}
+
+		tv := field.typ
 		if tv == nil {
-			p.logger.Warn("could not determine type for field", "field", fieldName)
+			if err := p.untranslated(p.fieldError(info, key, field, errors.New("could not determine the type of the field"))); err != nil {
+				errs = append(errs, err)
+			}
 			continue
 		}
 
-		rawRules := strings.Split(validateTag, ",")
-		celRules, err := p.processRules(rawRules, tv)
+		sr := &siblingRules{}
+		if hasTag {
+			parsed, err := validatetag.Parse(validateTag)
+			if err != nil {
+				errs = append(errs, p.fieldError(info, key, field, err))
+				continue
+			}
+			sr, err = p.extractSiblingRules(info, owner, fieldName, tv, parsed.Rules)
+			if err != nil {
+				errs = append(errs, p.fieldError(info, key, field, err))
+				continue
+			}
+		}
+
+		celRules, err := p.processRules(sr.rest, tv)
 		if err != nil {
-			p.logger.Warn("error processing rules", "field", fieldName, "error", err)
+			errs = append(errs, p.fieldError(info, key, field, err))
 			continue
 		}
+		atTag := func(err error) error { return p.fieldError(info, key, field, err) }
+		if err := p.checkRules(tv, celRules, atTag); err != nil {
+			errs = append(errs, err)
+		}
+		if err := p.checkRules(owner, append(sr.cross, sr.conditional...), atTag); err != nil {
+			errs = append(errs, err)
+		}
+		for _, c := range field.commentRules {
+			atComment := func(err error) error {
+				return p.positionError(info, c.Pos, fmt.Errorf("%s: field %s: %w", strings.TrimPrefix(key, info.PkgPath+"."), field.name, err))
+			}
+			if err := p.checkRules(tv, []string{c.Rule}, atComment); err != nil {
+				errs = append(errs, err)
+			}
+			celRules = append(celRules, c.Rule)
+		}
+
+		crossRules := sr.cross
+		if sr.omitEmpty {
+			// The field's own rules are skipped when it is zero; conditional requirements are not.
+			celRules = p.skipIfZero(celRules, tv, "self")
+			crossRules = p.skipIfZero(crossRules, tv, "self."+fieldName)
+		}
+		crossRules = append(crossRules, sr.conditional...)
+
+		if len(crossRules) > 0 {
+			if ruleSet.CrossFieldRules == nil {
+				ruleSet.CrossFieldRules = make(map[string][]string)
+			}
+			ruleSet.CrossFieldRules[fieldName] = crossRules
+		}
 		if len(celRules) > 0 {
 			ruleSet.FieldRules[fieldName] = celRules
 		}
 	}
+	return errors.Join(errs...)
 }
 
-func (p *Parser) getEmbeddedStruct(info PackageInfo, expr ast.Expr) (*ast.StructType, bool) {
-	typeOf := info.TypesInfo.TypeOf(expr)
-	if typeOf == nil {
-		return nil, false
+// fieldError adds the struct (by its key, without the package path), the field and its position to err.
+func (p *Parser) fieldError(info PackageInfo, key string, field structField, err error) error {
+	return p.positionError(info, field.pos, fmt.Errorf("%s: field %s: %w", strings.TrimPrefix(key, info.PkgPath+"."), field.name, err))
+}
+
+// positionError returns err as an *Error at pos.
+func (p *Parser) positionError(info PackageInfo, pos token.Pos, err error) error {
+	e := &Error{Pos: pos, Err: err}
+	if info.Fset != nil && pos.IsValid() {
+		e.Position = info.Fset.Position(pos)
+	}
+	return e
+}
+
+// checkTypeRules type-checks the `// @cel:` rules of doc against self, the type named name.
+func (p *Parser) checkTypeRules(info PackageInfo, name string, self types.Type, doc *ast.CommentGroup) error {
+	var errs []error
+	for _, c := range CELComments(doc) {
+		at := func(err error) error { return p.positionError(info, c.Pos, fmt.Errorf("%s: %w", name, err)) }
+		if err := p.checkRules(self, []string{c.Rule}, at); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// untranslated handles err, a rule that cannot be fully translated. In strict mode it is returned;
+// otherwise it is logged as a warning, nil is returned, and the rule is dropped.
+func (p *Parser) untranslated(err error) error {
+	if p.strict {
+		return err
+	}
+	if err != nil {
+		p.logger.Warn("rule dropped", "error", err)
 	}
+	return nil
+}
 
-	named, ok := typeOf.(*types.Named)
+// astFields returns the fields of a struct declared in the package being parsed.
+func (p *Parser) astFields(info PackageInfo, structType *ast.StructType) []structField {
+	var fields []structField
+	for _, field := range structType.Fields.List {
+		var tag string
+		pos := field.Pos()
+		if field.Tag != nil {
+			tag = strings.Trim(field.Tag.Value, "`")
+			pos = field.Tag.Pos()
+		}
+		tv := info.TypesInfo.TypeOf(field.Type)
+		if field.Names == nil {
+			fields = append(fields, structField{pos: pos, typ: tv, expr: field.Type, embedded: true, tag: tag})
+			continue
+		}
+		// Rules written as comments on the field, before it or at the end of its line.
+		commentRules := append(CELComments(field.Doc), CELComments(field.Comment)...)
+		for _, name := range field.Names {
+			fields = append(fields, structField{name: name.Name, pos: pos, typ: tv, expr: field.Type, tag: tag, commentRules: commentRules})
+		}
+	}
+	return fields
+}
+
+// extractAnonymousStruct collects the rules of field's type into nested[key], if it is an
+// anonymous struct, or a pointer, slice, array or map of one. The rules of anonymous structs
+// nested in it are collected too, under keys extending key.
+func (p *Parser) extractAnonymousStruct(info PackageInfo, key string, field structField, nested map[string]veritas.ValidationRuleSet) error {
+	st, structType, ok := anonymousStruct(field.typ, field.expr)
 	if !ok {
-		return nil, false
+		return nil
+	}
+	fields := typeFields(st)
+	if structType != nil {
+		fields = p.astFields(info, structType)
+	}
+
+	ruleSet := veritas.ValidationRuleSet{FieldRules: make(map[string][]string)}
+	if err := p.extractRulesForFields(info, st, key, fields, &ruleSet, nested, map[*types.Named]bool{}); err != nil {
+		return err
+	}
+	if len(ruleSet.FieldRules) > 0 || len(ruleSet.CrossFieldRules) > 0 {
+		nested[key] = ruleSet
 	}
+	return nil
+}
+
+// anonymousStruct returns the anonymous struct type t refers to, looking through pointers and
+// the elements of slices, arrays and maps, together with its syntax if expr is available.
+func anonymousStruct(t types.Type, expr ast.Expr) (*types.Struct, *ast.StructType, bool) {
+	for {
+		if paren, ok := expr.(*ast.ParenExpr); ok {
+			expr = paren.X
+			continue
+		}
+		var elemExpr ast.Expr
+		switch tt := t.(type) {
+		case *types.Struct:
+			structType, _ := expr.(*ast.StructType)
+			return tt, structType, true
+		case *types.Pointer:
+			t = tt.Elem()
+			if star, ok := expr.(*ast.StarExpr); ok {
+				elemExpr = star.X
+			}
+		case *types.Slice:
+			t = tt.Elem()
+			if array, ok := expr.(*ast.ArrayType); ok {
+				elemExpr = array.Elt
+			}
+		case *types.Array:
+			t = tt.Elem()
+			if array, ok := expr.(*ast.ArrayType); ok {
+				elemExpr = array.Elt
+			}
+		case *types.Map:
+			t = tt.Elem()
+			if m, ok := expr.(*ast.MapType); ok {
+				elemExpr = m.Value
+			}
+		default:
+			return nil, nil, false
+		}
+		expr = elemExpr
+	}
+}
+
+// typeFields returns the fields of a struct from its type information.
+// Comments are not available, so only the tags are read.
+func typeFields(st *types.Struct) []structField {
+	fields := make([]structField, st.NumFields())
+	for i := range fields {
+		f := st.Field(i)
+		fields[i] = structField{name: f.Name(), pos: f.Pos(), typ: f.Type(), embedded: f.Embedded(), tag: st.Tag(i)}
+	}
+	return fields
+}
+
+// siblingRules is the result of extractSiblingRules.
+type siblingRules struct {
+	rest        []*validatetag.Rule // The remaining rules, applied to the field's value.
+	cross       []string            // Comparisons with siblings, e.g. "self.StartAt < self.EndAt".
+	conditional []string            // Requirements depending on siblings, e.g. required_if.
+	omitEmpty   bool
+}
+
+// isSiblingRule reports whether r is omitempty or a shorthand referring to a sibling field.
+func isSiblingRule(r *validatetag.Rule) bool {
+	if r.Kind != validatetag.Shorthand {
+		return false
+	}
+	return IsFieldReference(r.Name) || r.Name == "omitempty"
+}
+
+// findSiblingRule returns the first omitempty or sibling shorthand in the bodies of directives.
+func findSiblingRule(rules []*validatetag.Rule) *validatetag.Rule {
+	for _, r := range rules {
+		if r.Kind == validatetag.Directive {
+			if found := findSiblingRule(r.Body); found != nil {
+				return found
+			}
+		} else if isSiblingRule(r) {
+			return r
+		}
+	}
+	return nil
+}
+
+// extractSiblingRules removes the shorthands that refer to sibling fields (e.g. "ltfield=EndAt",
+// "required_if=Kind company") and omitempty from rules. Sibling shorthands are converted into
+// rules on the owner type; the referenced sibling must exist and have a suitable type.
+func (p *Parser) extractSiblingRules(info PackageInfo, owner types.Type, fieldName string, tv types.Type, rules []*validatetag.Rule) (*siblingRules, error) {
+	sr := &siblingRules{}
+	self := "self." + fieldName
+	for _, r := range rules {
+		if r.Kind == validatetag.Directive {
+			// Shorthands after dive, keys or values apply to elements, not to the field.
+			if found := findSiblingRule(r.Body); found != nil {
+				return nil, ruleError(found, "%s cannot be used inside dive, keys or values", found.Name)
+			}
+		}
+		if !isSiblingRule(r) {
+			sr.rest = append(sr.rest, r)
+			continue
+		}
+
+		name := r.Name
+		if name == "omitempty" {
+			if len(r.Args) > 0 {
+				return nil, ruleError(r, "omitempty takes no arguments")
+			}
+			if _, _, ok := p.zeroCEL(tv, "self"); !ok {
+				return nil, ruleError(r, "omitempty is not applicable to %s", tv)
+			}
+			sr.omitEmpty = true
+			continue
+		}
+
+		needsValue := conditionalOps[name] // Always false for cross-field comparisons.
+		switch {
+		case len(r.Args) == 0:
+			return nil, ruleError(r, "%s requires a field name, e.g. %s=Other", name, name)
+		case needsValue && len(r.Args) != 2:
+			return nil, ruleError(r, "%s requires a field name and a value, e.g. %s=Other value", name, name)
+		case !needsValue && len(r.Args) != 1:
+			return nil, ruleError(r, "%s takes only a field name, e.g. %s=Other", name, name)
+		}
+		target := r.Args[0]
+		if target == fieldName {
+			return nil, ruleError(r, "%s refers to the field itself", name)
+		}
+		sibling := p.lookupField(info, owner, target)
+		if sibling == nil {
+			return nil, ruleError(r, "%s refers to unknown field %q", name, target)
+		}
+		other := "self." + target
+
+		if cross, ok := crossFieldOps[name]; ok {
+			if err := p.checkComparable(tv, sibling.Type(), cross.ordered); err != nil {
+				return nil, ruleError(r, "%s=%s: %s", name, target, err)
+			}
+			sr.cross = append(sr.cross, fmt.Sprintf("%s %s %s", self, cross.op, other))
+			continue
+		}
+
+		fieldZero, fieldNonzero, ok := p.zeroCEL(tv, self)
+		if !ok {
+			return nil, ruleError(r, "%s is not applicable to %s", name, tv)
+		}
+
+		var rule string
+		switch name {
+		case "required_if", "excluded_unless":
+			lit, err := p.celLiteral(r.Args[1], sibling.Type())
+			if err != nil {
+				return nil, ruleError(r, "%s: %s", r, err)
+			}
+			if name == "required_if" {
+				rule = fmt.Sprintf("%s != %s || %s", other, lit, fieldNonzero)
+			} else {
+				rule = fmt.Sprintf("%s == %s || %s", other, lit, fieldZero)
+			}
+		case "required_with", "required_without":
+			siblingZero, siblingNonzero, ok := p.zeroCEL(sibling.Type(), other)
+			if !ok {
+				return nil, ruleError(r, "%s: cannot tell whether %s is set", r, sibling.Type())
+			}
+			cond := siblingZero // required_with: skipped while the sibling is zero.
+			if name == "required_without" {
+				cond = siblingNonzero
+			}
+			rule = fmt.Sprintf("%s || %s", cond, fieldNonzero)
+		}
+		sr.conditional = append(sr.conditional, rule)
+	}
+	return sr, nil
+}
+
+// ruleError returns an error positioned at r in the tag.
+func ruleError(r *validatetag.Rule, format string, args ...any) error {
+	return &validatetag.Error{Offset: r.Pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// lookupField returns the field (possibly promoted from an embedded struct) named name of owner.
+func (p *Parser) lookupField(info PackageInfo, owner types.Type, name string) *types.Var {
+	if owner == nil {
+		return nil
+	}
+	obj, _, _ := types.LookupFieldOrMethod(owner, false, info.Types, name)
+	if v, ok := obj.(*types.Var); ok && v.IsField() {
+		return v
+	}
+	return nil
+}
+
+// zeroCEL returns CEL expressions testing whether varName holds (or does not hold) the zero value of tv.
+func (p *Parser) zeroCEL(tv types.Type, varName string) (zero, nonzero string, ok bool) {
+	category := p.categorizeType(tv)
+	if category == "ptr" && varName != "self" {
+		// Native types read nil pointer fields as zero values, so presence is checked with has().
+		// dyn() lets the null comparison type-check, for adapted objects where nil is null.
+		return fmt.Sprintf("(!has(%s) || dyn(%s) == null)", varName, varName),
+			fmt.Sprintf("(has(%s) && dyn(%s) != null)", varName, varName), true
+	}
+	expr, ok := zeroCELMap[category]
+	if !ok {
+		return "", "", false
+	}
+	return replaceSelf(expr.zero, varName), replaceSelf(expr.nonzero, varName), true
+}
 
+// skipIfZero rewrites rules so that they pass when varName holds the zero value of tv.
+func (p *Parser) skipIfZero(rules []string, tv types.Type, varName string) []string {
+	zero, _, _ := p.zeroCEL(tv, varName)
+	result := make([]string, len(rules))
+	for i, rule := range rules {
+		result[i] = fmt.Sprintf("%s || (%s)", zero, rule)
+	}
+	return result
+}
+
+// celLiteral converts value, taken from a tag, into a CEL literal of type t.
+func (p *Parser) celLiteral(value string, t types.Type) (string, error) {
+	switch p.categorizeType(t) {
+	case "string":
+		return strconv.Quote(value), nil
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", fmt.Errorf("%q is not a valid %s", value, t)
+		}
+		return value, nil
+	case "uint":
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return "", fmt.Errorf("%q is not a valid %s", value, t)
+		}
+		return value + "u", nil
+	case "float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid %s", value, t)
+		}
+		lit := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(lit, ".") {
+			lit += ".0"
+		}
+		return lit, nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid %s", value, t)
+		}
+		return strconv.FormatBool(b), nil
+	default:
+		return "", fmt.Errorf("cannot compare %s with a value", t)
+	}
+}
+
+func replaceSelf(expr, varName string) string {
+	return strings.ReplaceAll(expr, "self", varName)
+}
+
+// checkComparable reports whether values of types x and y can be compared in CEL.
+// Ordered comparisons are limited to numbers, strings and time.Time.
+func (p *Parser) checkComparable(x, y types.Type, ordered bool) error {
+	cx, cy := p.categorizeType(x), p.categorizeType(y)
+	basic := cx == "string" || cx == "int" || cx == "uint" || cx == "float"
+	switch {
+	case ordered && isTime(x) && isTime(y):
+		return nil
+	case ordered && basic && cx == cy:
+		return nil
+	case ordered:
+		return fmt.Errorf("cannot order %s and %s", x, y)
+	case types.Identical(x, y):
+		return nil
+	case (basic || cx == "bool") && cx == cy:
+		return nil
+	default:
+		return fmt.Errorf("cannot compare %s and %s", x, y)
+	}
+}
+
+func isTime(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
 	obj := named.Obj()
-	if obj == nil || obj.Pkg() != info.Types {
-		// Not defined in the same package, handling this would require finding the package and file.
-		// For now, we only support embedded structs from the same package.
+	return obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time"
+}
+
+// embeddedFields returns the fields of the struct embedded as t, which may be a pointer.
+// Structs declared in the package being parsed are read from the syntax, so that @cel comments
+// on their fields are found; structs from other packages are read from their type information.
+func (p *Parser) embeddedFields(info PackageInfo, t types.Type, seen map[*types.Named]bool) ([]structField, bool) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || seen[named] {
+		return nil, false
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
 		return nil, false
 	}
+	seen[named] = true
 
-	// Find the AST node for the type definition
+	obj := named.Obj()
+	if obj.Pkg() == info.Types && named.TypeArgs().Len() == 0 {
+		if structType, ok := p.findStructSyntax(info, obj.Name()); ok {
+			return p.astFields(info, structType), true
+		}
+	}
+	return typeFields(st), true
+}
+
+// findStructSyntax returns the declaration of the struct type name in the package being parsed.
+func (p *Parser) findStructSyntax(info PackageInfo, name string) (*ast.StructType, bool) {
 	for _, f := range info.Syntax {
 		for _, decl := range f.Decls {
 			genDecl, ok := decl.(*ast.GenDecl)
@@ -241,7 +1154,7 @@ func (p *Parser) getEmbeddedStruct(info PackageInfo, expr ast.Expr) (*ast.Struct
 				if !ok {
 					continue
 				}
-				if typeSpec.Name.Name == obj.Name() {
+				if typeSpec.Name.Name == name {
 					if structType, ok := typeSpec.Type.(*ast.StructType); ok {
 						return structType, true
 					}
@@ -249,35 +1162,24 @@ func (p *Parser) getEmbeddedStruct(info PackageInfo, expr ast.Expr) (*ast.Struct
 			}
 		}
 	}
-
 	return nil, false
 }
 
-func (p *Parser) processRules(rawRules []string, tv types.Type) ([]string, error) {
-	var celRules []string
-	remaining := rawRules
-	for len(remaining) > 0 {
-		rule, nextRemaining, err := p.parseRule(remaining, tv)
-		if err != nil {
-			return nil, err
-		}
-		cel, err := rule.ToCEL()
+// processRules converts the rules of a field into CEL expressions.
+// Consecutive simple rules are joined with "&&", while each directive becomes a rule of its own.
+func (p *Parser) processRules(rules []*validatetag.Rule, tv types.Type) ([]string, error) {
+	var finalRules []string
+	var simpleConditions []string
+
+	for _, r := range rules {
+		cel, err := p.ruleToCEL(r, tv, "self")
 		if err != nil {
 			return nil, err
 		}
-		if cel != "" {
-			celRules = append(celRules, cel)
+		if cel == "" {
+			continue
 		}
-		remaining = nextRemaining
-	}
-
-	// Post-process to join non-directive rules
-	var finalRules []string
-	var simpleConditions []string
-
-	for _, cel := range celRules {
-		// This is a heuristic: directives like `all()` are assumed to be standalone.
-		if strings.Contains(cel, ".all(") {
+		if r.Kind == validatetag.Directive {
 			if len(simpleConditions) > 0 {
 				finalRules = append(finalRules, strings.Join(simpleConditions, " && "))
 				simpleConditions = nil
@@ -294,155 +1196,98 @@ func (p *Parser) processRules(rawRules []string, tv types.Type) ([]string, error
 	return finalRules, nil
 }
 
-type Rule struct {
-	TV        types.Type
-	BaseVar   string
-	Directive string
-	SubRules  []string
-	Nested    []*Rule
-	parser    *Parser
-}
-
-func (r *Rule) ToCEL() (string, error) {
-	if r.Directive == "" {
-		var conditions []string
-		for _, shorthand := range r.SubRules {
-			if shorthand == "" {
-				continue
-			}
-			cel := r.parser.shorthandToCEL(shorthand, r.TV, r.BaseVar)
-			if cel != "" {
-				conditions = append(conditions, cel)
-			}
-		}
-		return strings.Join(conditions, " && "), nil
-	}
-
-	var varName string
-	switch r.Directive {
-	case "dive":
-		varName = "x"
-	case "keys":
-		varName = "k"
-	case "values":
-		varName = "v"
+// RuleCEL converts r, a rule of a validate tag, into the CEL expression it is generated as,
+// with 'self' being a value of type t. omitempty and the rules that refer to other fields
+// (e.g. "eqfield=Other") depend on the rest of the struct, and cannot be converted alone.
+func (p *Parser) RuleCEL(r *validatetag.Rule, t types.Type) (string, error) {
+	if r.Kind == validatetag.Shorthand && (r.Name == "omitempty" || IsFieldReference(r.Name)) {
+		return "", ruleError(r, "%s cannot be converted without its struct", r.Name)
 	}
-
-	var nestedCELs []string
-	for _, nestedRule := range r.Nested {
-		nestedRule.BaseVar = varName
-		cel, err := nestedRule.ToCEL()
-		if err != nil {
-			return "", err
-		}
-		nestedCELs = append(nestedCELs, cel)
-	}
-
-	return fmt.Sprintf("%s.all(%s, %s)", r.BaseVar, varName, strings.Join(nestedCELs, " && ")), nil
+	return p.ruleToCEL(r, t, "self")
 }
 
-func (p *Parser) parseRule(rawRules []string, tv types.Type) (*Rule, []string, error) {
-	if len(rawRules) == 0 {
-		return nil, nil, nil
+// ruleToCEL converts r, applied to varName of type tv, into a CEL expression.
+// Directives become "all()" macros over their collection, with their body applied to each item.
+func (p *Parser) ruleToCEL(r *validatetag.Rule, tv types.Type, varName string) (string, error) {
+	switch r.Kind {
+	case validatetag.CEL:
+		return replaceSelf(r.Expr, varName), nil
+	case validatetag.Shorthand:
+		return p.shorthandToCEL(r, tv, varName)
 	}
-	token := strings.TrimSpace(rawRules[0])
-
-	rule := &Rule{TV: tv, BaseVar: "self", parser: p}
 
-	switch token {
+	var itemType types.Type
+	var itemVar, iterVar string
+	switch r.Name {
 	case "dive":
 		slice, ok := tv.Underlying().(*types.Slice)
 		if !ok {
-			return nil, nil, fmt.Errorf("'dive' on non-slice type: %s", tv.String())
+			return "", ruleError(r, "'dive' on non-slice type: %s", tv.String())
 		}
-		rule.Directive = "dive"
-
-		var nestedRules []*Rule
-		remaining := rawRules[1:]
-		for len(remaining) > 0 {
-			var nested *Rule
-			var err error
-			nested, remaining, err = p.parseRule(remaining, slice.Elem())
-			if err != nil {
-				return nil, nil, err
-			}
-			nestedRules = append(nestedRules, nested)
-		}
-		rule.Nested = nestedRules
-		return rule, remaining, nil
-	case "keys":
+		itemType, itemVar = slice.Elem(), "x"
+	case "keys", "values":
 		m, ok := tv.Underlying().(*types.Map)
 		if !ok {
-			return nil, nil, fmt.Errorf("'keys' on non-map type: %s", tv.String())
+			return "", ruleError(r, "'%s' on non-map type: %s", r.Name, tv.String())
 		}
-		rule.Directive = "keys"
-		nested, remaining, err := p.parseRule(rawRules[1:], m.Key())
-		if err != nil {
-			return nil, nil, err
-		}
-		rule.Nested = []*Rule{nested}
-		return rule, remaining, nil
-	case "values":
-		m, ok := tv.Underlying().(*types.Map)
-		if !ok {
-			return nil, nil, fmt.Errorf("'values' on non-map type: %s", tv.String())
+		if r.Name == "keys" {
+			itemType, itemVar = m.Key(), "k"
+		} else {
+			// Macros on maps range over the keys, so the values are reached by indexing.
+			iterVar = freshVar("k", varName)
+			itemType, itemVar = m.Elem(), fmt.Sprintf("%s[%s]", varName, iterVar)
 		}
-		rule.Directive = "values"
-		nested, remaining, err := p.parseRule(rawRules[1:], m.Elem())
+	}
+	if iterVar == "" {
+		iterVar = itemVar
+	}
+
+	var conditions []string
+	for _, b := range r.Body {
+		cel, err := p.ruleToCEL(b, itemType, itemVar)
 		if err != nil {
-			return nil, nil, err
+			return "", err
 		}
-		rule.Nested = []*Rule{nested}
-		return rule, remaining, nil
-	default:
-		// Check if the first token starts a CEL expression.
-		if strings.HasPrefix(token, "cel:") {
-			// Find where the CEL expression ends. It might span multiple "tokens"
-			// if there are commas within the CEL expression itself.
-			// This is a simplification; a truly robust solution would need a more
-			// sophisticated parser. For now, we assume CEL expressions don't contain
-			// the 'dive', 'keys', or 'values' keywords and that they are the last rule.
-			var celExprBuilder strings.Builder
-			celExprBuilder.WriteString(strings.TrimPrefix(token, "cel:"))
-
-			remaining := rawRules[1:]
-			end := 0
-			for i, t := range remaining {
-				trimmed := strings.TrimSpace(t)
-				if trimmed == "dive" || trimmed == "keys" || trimmed == "values" {
-					break
-				}
-				celExprBuilder.WriteString(",")
-				celExprBuilder.WriteString(t)
-				end = i + 1
-			}
-			rule.SubRules = []string{"cel:" + celExprBuilder.String()}
-			return rule, remaining[end:], nil
+		if cel != "" {
+			conditions = append(conditions, cel)
 		}
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("%s.all(%s, %s)", varName, iterVar, strings.Join(conditions, " && ")), nil
+}
 
-		// Find end of shorthands if not a CEL expression
-		end := 0
-		for i, t := range rawRules {
-			trimmed := strings.TrimSpace(t)
-			if trimmed == "dive" || trimmed == "keys" || trimmed == "values" || strings.HasPrefix(trimmed, "cel:") {
-				break
-			}
-			end = i + 1
-		}
-		rule.SubRules = rawRules[:end]
-		return rule, rawRules[end:], nil
+// freshVar returns base, or base followed by a number, so that it is not an identifier in expr.
+func freshVar(base, expr string) string {
+	idents := strings.FieldsFunc(expr, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	name := base
+	for i := 2; slices.Contains(idents, name); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
 	}
+	return name
 }
 
-func (p *Parser) shorthandToCEL(shorthand string, tv types.Type, varName string) string {
-	if strings.HasPrefix(shorthand, "cel:") {
-		return strings.ReplaceAll(strings.TrimPrefix(shorthand, "cel:"), "self", varName)
+func (p *Parser) shorthandToCEL(r *validatetag.Rule, tv types.Type, varName string) (string, error) {
+	if r.Name == "enum" {
+		if len(r.Args) > 0 {
+			return "", ruleError(r, "enum takes no arguments")
+		}
+		cel, err := p.enumCEL(tv, varName)
+		if err != nil {
+			return "", ruleError(r, "enum: %s", err)
+		}
+		return cel, nil
 	}
 
-	cel, ok := shorthandCELMap[shorthand]
+	cel, ok := shorthandCELMap[r.Name]
 	if !ok {
-		p.logger.Warn("unsupported validation shorthand", "shorthand", shorthand)
-		return ""
+		return "", p.untranslated(ruleError(r, "unknown validation shorthand %q", r.Name))
+	}
+	if len(r.Args) > 0 {
+		return "", ruleError(r, "%s takes no arguments", r.Name)
 	}
 
 	var exprTpl string
@@ -454,11 +1299,10 @@ func (p *Parser) shorthandToCEL(shorthand string, tv types.Type, varName string)
 		var tplOk bool
 		exprTpl, tplOk = v[typeCategory]
 		if !tplOk {
-			p.logger.Warn("shorthand not applicable for type category", "shorthand", shorthand, "category", typeCategory)
-			return ""
+			return "", p.untranslated(ruleError(r, "%s is not applicable to %s", r.Name, tv))
 		}
 	}
-	return strings.ReplaceAll(exprTpl, "self", varName)
+	return replaceSelf(exprTpl, varName), nil
 }
 
 func (p *Parser) categorizeType(tv types.Type) string {
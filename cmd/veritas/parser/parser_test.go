@@ -1,10 +1,19 @@
 package parser
 
 import (
+	"go/ast"
+	"go/format"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/google/cel-go/cel"
 	"github.com/google/go-cmp/cmp"
 	"github.com/podhmo/veritas"
 )
@@ -22,6 +31,19 @@ func TestParser(t *testing.T) {
 					"ID": {`self != "" && self.size() > 1`},
 				},
 			},
+			pkgPrefix + "AuditedUser": {
+				FieldRules: map[string][]string{
+					"CreatedBy": {`self != ""`},
+					"Revision":  {`self >= 0`},
+					"ID":        {`self != "" && self.size() > 1`},
+				},
+			},
+			pkgPrefix + "Box[*github.com/podhmo/veritas/testdata/sources.Item]": {
+				TypeRules: []string{"self.Value != null"},
+				FieldRules: map[string][]string{
+					"Value": {`self != null`},
+				},
+			},
 			pkgPrefix + "Box[T]": {
 				TypeRules: []string{"self.Value != null"},
 				FieldRules: map[string][]string{
@@ -50,7 +72,7 @@ func TestParser(t *testing.T) {
 					"UserEmails": {`self.all(x, x.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$'))`},
 					"ResourceMap": {
 						`self.all(k, k.startsWith('id_'))`,
-						`self.all(v, v != null)`,
+						`self.all(k, self[k] != null)`,
 					},
 					"Users":  {`self.all(x, x != null)`},
 					"Matrix": {`self.all(x, x.all(x, x != 0))`},
@@ -59,11 +81,11 @@ func TestParser(t *testing.T) {
 			pkgPrefix + "MockMoreComplexData": {
 				FieldRules: map[string][]string{
 					"ListOfMaps": {
-						`self.all(x, x.size() > 0 && x.all(k, k.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$')) && x.all(v, v != ""))`,
+						`self.all(x, x.size() > 0 && x.all(k, k.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$')) && x.all(k, x[k] != ""))`,
 					},
 					"MapOfSlices": {
 						`self.all(k, k != "")`,
-						`self.all(v, v.all(x, x != ""))`,
+						`self.all(k, self[k].all(x, x != ""))`,
 					},
 				},
 			},
@@ -83,12 +105,43 @@ func TestParser(t *testing.T) {
 					"Metadata": {"self.size() > 0"},
 				},
 			},
+			pkgPrefix + "Order": {
+				FieldRules: map[string][]string{
+					"Kind":      {`self != ""`},
+					"Email":     {`self == "" || (self.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$'))`},
+					"Reference": {`self == "" || (self.startsWith('REF-'))`},
+				},
+				CrossFieldRules: map[string][]string{
+					"Company":   {`self.Kind != "company" || self.Company != ""`},
+					"TaxID":     {`self.Kind == "company" || (!has(self.TaxID) || dyn(self.TaxID) == null)`},
+					"Phone":     {`self.Email != "" || self.Phone != ""`},
+					"Quantity":  {`self.Note == "" || self.Quantity != 0u`},
+					"Reference": {`self.Reference == "" || (self.Reference != self.Note)`},
+				},
+			},
+			pkgPrefix + "Percent": {
+				TypeRules:  []string{"self >= 0 && self <= 100"},
+				FieldRules: map[string][]string{},
+			},
+			pkgPrefix + "Tags": {
+				TypeRules:  []string{"self.size() <= 3"},
+				FieldRules: map[string][]string{},
+			},
 			pkgPrefix + "Profile": {
 				FieldRules: map[string][]string{
 					"Platform": {`self != ""`},
 					"Handle":   {`self != "" && self.size() > 2`},
 				},
 			},
+			pkgPrefix + "SignupForm": {
+				FieldRules: map[string][]string{
+					"Password": {`self != ""`},
+				},
+				CrossFieldRules: map[string][]string{
+					"Password": {"self.Password == self.PasswordConfirm"},
+					"EndAt":    {"self.EndAt > self.StartAt"},
+				},
+			},
 			pkgPrefix + "UserWithProfiles": {
 				FieldRules: map[string][]string{
 					"Name": {`self != ""`},
@@ -107,3 +160,635 @@ func TestParser(t *testing.T) {
 		}
 	})
 }
+
+// TestParser_GeneratedRulesEvaluate runs generated rules against real values. Rules on the values
+// of maps used to iterate over the keys, and nonzero on unsigned integers compared them with an int.
+func TestParser_GeneratedRulesEvaluate(t *testing.T) {
+	got, err := parseSource(t, "package p\n"+
+		"type Form struct {\n"+
+		"	Labels map[string]string `validate:\"keys,nonzero,values,nonzero\"`\n"+
+		"	Count  uint              `validate:\"nonzero\"`\n"+
+		"}\n")
+	if err != nil {
+		t.Fatalf("ParseDirectly() error = %v", err)
+	}
+	rules := got["p.Form"].FieldRules
+
+	tests := []struct {
+		name  string
+		rule  string
+		self  *cel.Type
+		value any
+		want  bool
+	}{
+		{name: "empty value", rule: rules["Labels"][1], self: cel.MapType(cel.StringType, cel.StringType), value: map[string]string{"env": ""}, want: false},
+		{name: "non-empty value", rule: rules["Labels"][1], self: cel.MapType(cel.StringType, cel.StringType), value: map[string]string{"env": "prod"}, want: true},
+		{name: "empty key", rule: rules["Labels"][0], self: cel.MapType(cel.StringType, cel.StringType), value: map[string]string{"": "prod"}, want: false},
+		{name: "zero uint", rule: rules["Count"][0], self: cel.UintType, value: uint(0), want: false},
+		{name: "non-zero uint", rule: rules["Count"][0], self: cel.UintType, value: uint(3), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := cel.NewEnv(cel.Variable("self", tt.self))
+			if err != nil {
+				t.Fatalf("cel.NewEnv() error = %v", err)
+			}
+			ast, issues := env.Compile(tt.rule)
+			if issues.Err() != nil {
+				t.Fatalf("rule %q does not compile: %v", tt.rule, issues.Err())
+			}
+			prg, err := env.Program(ast)
+			if err != nil {
+				t.Fatalf("env.Program() error = %v", err)
+			}
+			out, _, err := prg.Eval(map[string]any{"self": tt.value})
+			if err != nil {
+				t.Fatalf("rule %q: Eval() error = %v", tt.rule, err)
+			}
+			if out.Value() != tt.want {
+				t.Errorf("rule %q on %v = %v, want %v", tt.rule, tt.value, out.Value(), tt.want)
+			}
+		})
+	}
+}
+
+// parseSource type-checks src as package "p" and parses its rules with ParseDirectly.
+func parseSource(t *testing.T, src string) (map[string]veritas.ValidationRuleSet, error) {
+	t.Helper()
+	p := NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	rules, _, err := p.ParseDirectly(loadSource(t, src))
+	return rules, err
+}
+
+// loadSource type-checks src as the package "p".
+func loadSource(t *testing.T, src string) PackageInfo {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := goparser.ParseFile(fset, "p.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("p", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatalf("failed to type-check source: %v", err)
+	}
+
+	return PackageInfo{PkgPath: "p", Syntax: []*ast.File{f}, TypesInfo: info, Types: pkg, Fset: fset}
+}
+
+func TestParser_CrossField(t *testing.T) {
+	t.Run("promoted and named fields", func(t *testing.T) {
+		got, err := parseSource(t, "package p\n"+
+			"type Level int\n"+
+			"type Range struct { Min Level }\n"+
+			"type Limits struct {\n"+
+			"	Range\n"+
+			"	Max Level `validate:\"gtefield=Min,nefield=Min\"`\n"+
+			"}\n")
+		if err != nil {
+			t.Fatalf("ParseDirectly() error = %v", err)
+		}
+		want := map[string]veritas.ValidationRuleSet{
+			"p.Limits": {
+				FieldRules: map[string][]string{},
+				CrossFieldRules: map[string][]string{
+					"Max": {"self.Max >= self.Min", "self.Max != self.Min"},
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("bool values", func(t *testing.T) {
+		// Every form that strconv.ParseBool accepts is a CEL bool literal.
+		got, err := parseSource(t, "package p\n"+
+			"type Form struct {\n"+
+			"	Flag  bool\n"+
+			"	One   string `validate:\"required_if=Flag 1\"`\n"+
+			"	Short string `validate:\"required_if=Flag t\"`\n"+
+			"	Upper string `validate:\"excluded_unless=Flag TRUE\"`\n"+
+			"	Off   string `validate:\"required_if=Flag 0\"`\n"+
+			"}\n")
+		if err != nil {
+			t.Fatalf("ParseDirectly() error = %v", err)
+		}
+		want := map[string][]string{
+			"One":   {`self.Flag != true || self.One != ""`},
+			"Short": {`self.Flag != true || self.Short != ""`},
+			"Upper": {`self.Flag == true || self.Upper == ""`},
+			"Off":   {`self.Flag != false || self.Off != ""`},
+		}
+		if diff := cmp.Diff(want, got["p.Form"].CrossFieldRules); diff != "" {
+			t.Errorf("CrossFieldRules mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	errorCases := []struct {
+		name string
+		tag  string
+		want string
+	}{
+		{name: "unknown field", tag: "eqfield=Missing", want: `refers to unknown field "Missing"`},
+		{name: "incompatible type", tag: "eqfield=Count", want: "cannot compare string and int"},
+		{name: "unordered type", tag: "ltfield=Tags", want: "cannot order string and []string"},
+		{name: "self reference", tag: "eqfield=Name", want: "refers to the field itself"},
+		{name: "inside dive", tag: "dive,eqfield=Other", want: "cannot be used inside dive"},
+		{name: "missing field name", tag: "nefield", want: "requires a field name"},
+		{name: "required_if without value", tag: "required_if=Other", want: "requires a field name and a value"},
+		{name: "required_with with value", tag: "required_with=Other x", want: "takes only a field name"},
+		{name: "required_if with invalid value", tag: "required_if=Count many", want: `"many" is not a valid int`},
+		{name: "excluded_unless unknown field", tag: "excluded_unless=Missing x", want: `refers to unknown field "Missing"`},
+		{name: "omitempty inside dive", tag: "dive,omitempty", want: "cannot be used inside dive"},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSource(t, "package p\n"+
+				"type Form struct {\n"+
+				"	Name  string `validate:\""+tt.tag+"\"`\n"+
+				"	Other string\n"+
+				"	Count int\n"+
+				"	Tags  []string\n"+
+				"}\n")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseDirectly() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_TagGrammar(t *testing.T) {
+	t.Run("CEL expressions are not split", func(t *testing.T) {
+		got, err := parseSource(t, "package p\n"+
+			"type Form struct {\n"+
+			"	Mode string `validate:\"cel:self in ['dive', 'a,b'],nonzero\"`\n"+
+			"	Tags []string `validate:\"dive,cel:self.matches('^[a-z]{1,3}$'),nonzero\"`\n"+
+			"}\n")
+		if err != nil {
+			t.Fatalf("ParseDirectly() error = %v", err)
+		}
+		want := map[string]veritas.ValidationRuleSet{
+			"p.Form": {
+				FieldRules: map[string][]string{
+					"Mode": {`self in ['dive', 'a,b'] && self != ""`},
+					"Tags": {`self.all(x, x.matches('^[a-z]{1,3}$') && x != "")`},
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	errorCases := []struct {
+		name string
+		tag  string
+		want string
+	}{
+		{name: "syntax error", tag: "cel:f(1", want: `field Name: validate tag: column 6: unclosed '(' in CEL expression`},
+		{name: "dive on non-slice", tag: "dive,nonzero", want: "field Name: validate tag: column 1: 'dive' on non-slice type: string"},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSource(t, "package p\n"+
+				"type Form struct {\n"+
+				"	Name string `validate:\""+tt.tag+"\"`\n"+
+				"}\n")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseDirectly() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown shorthands are dropped unless strict", func(t *testing.T) {
+		src := "package p\n" +
+			"type Form struct {\n" +
+			"	Name string `validate:\"nonzero,nonempty\"`\n" +
+			"}\n"
+		got, err := parseSource(t, src)
+		if err != nil {
+			t.Fatalf("ParseDirectly() error = %v", err)
+		}
+		want := map[string]veritas.ValidationRuleSet{
+			"p.Form": {FieldRules: map[string][]string{"Name": {`self != ""`}}},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+		}
+
+		p := NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)), WithStrict())
+		_, _, err = p.ParseDirectly(loadSource(t, src))
+		wantErr := `field Name: validate tag: column 9: unknown validation shorthand "nonempty"`
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("ParseDirectly() error = %v, want containing %q", err, wantErr)
+		}
+	})
+}
+
+func TestParser_CommentDirectives(t *testing.T) {
+	got, err := parseSource(t, `package p
+
+// @cel: self.ignored
+// The group comment belongs to no type.
+type (
+	// @cel: self.Min <= self.Max
+	Range struct {
+		Min int
+		Max int
+	}
+
+	// @cel: self.StartAt < self.EndAt &&
+	//     self.EndAt - self.StartAt <= 3600
+	// Slot is a time slot.
+	Slot struct {
+		StartAt int64
+		EndAt   int64
+	}
+)
+
+// @cel: self.Name != self.Nickname
+type Profile struct {
+	// @cel: self.size() <= 32
+	Name string `+"`validate:\"nonzero\"`"+`
+
+	// The nickname.
+	// @cel: self == '' ||
+	//   self.matches('^[a-z]+$')
+	Nickname string
+
+	Bio string // @cel: self.size() < 200
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseDirectly() error = %v", err)
+	}
+	want := map[string]veritas.ValidationRuleSet{
+		"p.Range": {
+			TypeRules:  []string{"self.Min <= self.Max"},
+			FieldRules: map[string][]string{},
+		},
+		"p.Slot": {
+			TypeRules:  []string{"self.StartAt < self.EndAt && self.EndAt - self.StartAt <= 3600"},
+			FieldRules: map[string][]string{},
+		},
+		"p.Profile": {
+			TypeRules: []string{"self.Name != self.Nickname"},
+			FieldRules: map[string][]string{
+				"Name":     {`self != ""`, "self.size() <= 32"},
+				"Nickname": {"self == '' || self.matches('^[a-z]+$')"},
+				"Bio":      {"self.size() < 200"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParser_CommentDirectives_Gofmt(t *testing.T) {
+	// gofmt turns the continuation in the doc comment of a top-level type into a code block,
+	// after a blank line.
+	src, err := format.Source([]byte(`package p
+
+// Event is an event.
+// @cel: self.StartAt < self.EndAt &&
+//     self.EndAt - self.StartAt <= 3600
+//
+// Events are sorted by StartAt.
+type Event struct {
+	StartAt int64
+	EndAt   int64
+}
+`))
+	if err != nil {
+		t.Fatalf("format.Source() error = %v", err)
+	}
+	if !strings.Contains(string(src), "&&\n//\n//\tself.EndAt") {
+		t.Fatalf("format.Source() did not make a code block of the continuation:\n%s", src)
+	}
+	got, err := parseSource(t, string(src))
+	if err != nil {
+		t.Fatalf("ParseDirectly() error = %v", err)
+	}
+	want := []string{"self.StartAt < self.EndAt && self.EndAt - self.StartAt <= 3600"}
+	if diff := cmp.Diff(want, got["p.Event"].TypeRules); diff != "" {
+		t.Errorf("TypeRules mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParser_NamedTypeRules(t *testing.T) {
+	_, err := parseSource(t, "package p\n"+
+		"// @cel: self != null\n"+
+		"type Handler func()\n")
+	if err == nil || !strings.Contains(err.Error(), "Handler: rules are only supported on") {
+		t.Errorf("ParseDirectly() error = %v", err)
+	}
+}
+
+func TestParser_Enum(t *testing.T) {
+	got, err := parseSource(t, `package p
+
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusInactive Status = "inactive"
+)
+
+const StatusBanned Status = "banned"
+
+const Unrelated = "unrelated"
+
+// @veritas:enum
+type Level uint8
+
+const (
+	LevelLow Level = iota + 1
+	LevelHigh
+)
+
+type Account struct {
+	Status  Status   `+"`validate:\"enum\"`"+`
+	Former  *Status  `+"`validate:\"omitempty,enum\"`"+`
+	History []Status `+"`validate:\"dive,enum\"`"+`
+	Level   Level
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseDirectly() error = %v", err)
+	}
+	want := map[string]veritas.ValidationRuleSet{
+		"p.Level": {
+			TypeRules:  []string{"self in [1u, 2u]"},
+			FieldRules: map[string][]string{},
+		},
+		"p.Account": {
+			FieldRules: map[string][]string{
+				"Status":  {`self in ["active", "inactive", "banned"]`},
+				"Former":  {`self == null || (self in ["active", "inactive", "banned"])`},
+				"History": {`self.all(x, x in ["active", "inactive", "banned"])`},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = parseSource(t, "package p\n"+
+		"type Kind string\n"+
+		"type Item struct {\n"+
+		"	Kind Kind `validate:\"enum\"`\n"+
+		"}\n")
+	if err == nil || !strings.Contains(err.Error(), "enum: no constants of type p.Kind are declared in p") {
+		t.Errorf("ParseDirectly() error = %v", err)
+	}
+}
+
+func TestParser_AnonymousStructs(t *testing.T) {
+	got, err := parseSource(t, "package p\n"+
+		"type User struct {\n"+
+		"	Name string `validate:\"nonzero\"`\n"+
+		"	Address struct {\n"+
+		"		Zip string `validate:\"nonzero\"`\n"+
+		"		// @cel: self.size() < 100\n"+
+		"		Street string\n"+
+		"		Geo *struct {\n"+
+		"			Lat float64 `validate:\"cel:self >= -90.0 && self <= 90.0\"`\n"+
+		"		}\n"+
+		"	}\n"+
+		"	Phones []struct {\n"+
+		"		Number string `validate:\"nonzero\"`\n"+
+		"		Backup string `validate:\"nefield=Number\"`\n"+
+		"	}\n"+
+		"	Labels map[string]struct{ Text string }\n"+
+		"}\n"+
+		"type Empty struct {\n"+
+		"	Meta struct {\n"+
+		"		Note string `validate:\"nonzero\"`\n"+
+		"	}\n"+
+		"}\n")
+	if err != nil {
+		t.Fatalf("ParseDirectly() failed: %v", err)
+	}
+	want := map[string]veritas.ValidationRuleSet{
+		"p.User": {
+			FieldRules: map[string][]string{"Name": {`self != ""`}},
+		},
+		"p.User.Address": {
+			FieldRules: map[string][]string{
+				"Zip":    {`self != ""`},
+				"Street": {`self.size() < 100`},
+			},
+		},
+		"p.User.Address.Geo": {
+			FieldRules: map[string][]string{"Lat": {`self >= -90.0 && self <= 90.0`}},
+		},
+		"p.User.Phones": {
+			FieldRules:      map[string][]string{"Number": {`self != ""`}},
+			CrossFieldRules: map[string][]string{"Backup": {`self.Backup != self.Number`}},
+		},
+		"p.Empty.Meta": {
+			FieldRules: map[string][]string{"Note": {`self != ""`}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParser_Instantiate(t *testing.T) {
+	t.Run("per-instantiation rules", func(t *testing.T) {
+		p := NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		got, knownTypes, err := p.ParseDirectly(loadSource(t, "package p\n"+
+			"import \"time\"\n"+
+			"type Item struct{ At time.Time }\n"+
+			"// @veritas:instantiate Pair[string, *Item]\n"+
+			"// @veritas:instantiate Pair[int, time.Time]\n"+
+			"type Pair[K comparable, V any] struct {\n"+
+			"	Key   K `validate:\"nonzero\"`\n"+
+			"	Value V `validate:\"required\"`\n"+
+			"}\n"+
+			"// @veritas:instantiate Box[any]\n"+
+			"type Box[T any] struct {\n"+
+			"	// @cel: self != null\n"+
+			"	Value T\n"+
+			"}\n"))
+		if err != nil {
+			t.Fatalf("ParseDirectly() failed: %v", err)
+		}
+		want := map[string]veritas.ValidationRuleSet{
+			"p.Pair[K, V]": {
+				FieldRules: map[string][]string{
+					"Key":   {`self != null`},
+					"Value": {`self != null`},
+				},
+			},
+			"p.Pair[string,*p.Item]": {
+				FieldRules: map[string][]string{
+					"Key":   {`self != ""`},
+					"Value": {`self != null`},
+				},
+			},
+			"p.Pair[int,time.Time]": {
+				FieldRules: map[string][]string{"Key": {`self != 0`}},
+			},
+			"p.Box[T]": {
+				FieldRules: map[string][]string{"Value": {`self != null`}},
+			},
+			"p.Box[interface {}]": {
+				FieldRules: map[string][]string{"Value": {`self != null`}},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+		}
+
+		imports := map[string]string{}
+		var exprs []string
+		for _, ti := range knownTypes {
+			exprs = append(exprs, ti.Expr("validation", imports))
+		}
+		wantExprs := []string{"p.Pair[string, *p.Item]", "p.Pair[int, time.Time]", "p.Box[any]"}
+		if diff := cmp.Diff(wantExprs, exprs); diff != "" {
+			t.Errorf("known types mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff(map[string]string{"p": "p", "time": "time"}, imports); diff != "" {
+			t.Errorf("imports mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid directives", func(t *testing.T) {
+		_, err := parseSource(t, "package p\n"+
+			"type Other struct{}\n"+
+			"// @veritas:instantiate Box[Missing]\n"+
+			"// @veritas:instantiate Other\n"+
+			"type Box[T comparable] struct {\n"+
+			"	Value T `validate:\"nonzero\"`\n"+
+			"}\n")
+		if err == nil {
+			t.Fatal("ParseDirectly() succeeded, want errors")
+		}
+		for _, want := range []string{
+			"Box: invalid @veritas:instantiate Box[Missing]",
+			"Box: @veritas:instantiate Other is not an instantiation of Box",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not contain %q", err, want)
+			}
+		}
+	})
+}
+
+func TestParser_Strict(t *testing.T) {
+	const src = "package p\n" +
+		"type Form struct {\n" +
+		"	Name  string `validate:\"nonzero,required\"`\n" +
+		"	Items []int  `validate:\"dive,required\"`\n" +
+		"	Count *int   `validate:\"required\"`\n" +
+		"}\n"
+
+	t.Run("not strict", func(t *testing.T) {
+		p := NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		got, _, err := p.ParseDirectly(loadSource(t, src))
+		if err != nil {
+			t.Fatalf("ParseDirectly() failed: %v", err)
+		}
+		want := map[string]veritas.ValidationRuleSet{
+			"p.Form": {
+				FieldRules: map[string][]string{
+					"Name":  {`self != ""`},
+					"Count": {`self != null`},
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		p := NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)), WithStrict())
+		_, _, err := p.ParseDirectly(loadSource(t, src))
+		if err == nil {
+			t.Fatal("ParseDirectly() succeeded, want errors")
+		}
+		for _, want := range []string{
+			"p.go:3:15: Form: field Name: validate tag: column 9: required is not applicable to string",
+			"p.go:4:15: Form: field Items: validate tag: column 6: required is not applicable to int",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not contain %q", err, want)
+			}
+		}
+	})
+}
+
+func TestParser_TypeCheck(t *testing.T) {
+	const src = "package p\n" +
+		"import \"time\"\n" +
+		"type Address struct{ City string }\n" +
+		"// @cel: self.Agee >= 18\n" +
+		"type User struct {\n" +
+		"	Age     int               `validate:\"cel:self.size() > 0\"`\n" +
+		"	Address *Address          // @cel: self.Cty != \"\"\n" +
+		"	Nick    *string           `validate:\"cel:self == null || self.size() > 2\"`\n" +
+		"	Tags    map[string]string `validate:\"values,cel:self.startsWith('x')\"`\n" +
+		"	Born    time.Time         `validate:\"cel:isAdult(self) && self < timestamp('2020-01-01T00:00:00Z')\"`\n" +
+		"	Died    time.Time         `validate:\"gtfield=Born\"`\n" +
+		"}\n"
+
+	// Undeclared functions such as isAdult may be registered at runtime, so they are not reported.
+	wantErr := strings.Join([]string{
+		`p.go:4:1: User: rule "self.Agee >= 18" does not compile: undefined field 'Agee'`,
+		`p.go:6:28: User: field Age: rule "self.size() > 0" does not compile: found no matching overload for 'size' applied to 'int.()'`,
+		`p.go:7:28: User: field Address: rule "self.Cty != \"\"" does not compile: undefined field 'Cty'`,
+	}, "\n")
+	for _, tt := range []struct {
+		name string
+		opts []ParserOption
+	}{
+		{name: "by default"},
+		{name: "strict", opts: []ParserOption{WithStrict()}},
+		{name: "strict with uncompilable rules", opts: []ParserOption{WithStrict(), WithUncompilableRules()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)), tt.opts...)
+			_, _, err := p.ParseDirectly(loadSource(t, src))
+			if err == nil {
+				t.Fatal("ParseDirectly() succeeded, want errors")
+			}
+			if err.Error() != wantErr {
+				t.Errorf("ParseDirectly() error =\n%s\nwant\n%s", err, wantErr)
+			}
+		})
+	}
+
+	t.Run("with uncompilable rules", func(t *testing.T) {
+		p := NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)), WithUncompilableRules())
+		got, _, err := p.ParseDirectly(loadSource(t, src))
+		if err != nil {
+			t.Fatalf("ParseDirectly() failed: %v", err)
+		}
+		// Rules that do not compile are kept, so that the Validator reports them too.
+		want := veritas.ValidationRuleSet{
+			TypeRules: []string{"self.Agee >= 18"},
+			FieldRules: map[string][]string{
+				"Age":     {"self.size() > 0"},
+				"Address": {`self.Cty != ""`},
+				"Nick":    {"self == null || self.size() > 2"},
+				"Tags":    {"self.all(k, self[k].startsWith('x'))"},
+				"Born":    {"isAdult(self) && self < timestamp('2020-01-01T00:00:00Z')"},
+			},
+			CrossFieldRules: map[string][]string{"Died": {"self.Died > self.Born"}},
+		}
+		if diff := cmp.Diff(want, got["p.User"]); diff != "" {
+			t.Errorf("ParseDirectly() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package veritas
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+)
+
+// WithCostBudget rejects rules whose estimated worst-case cost is over budget, so that rules
+// loaded from an untrusted source (e.g. a JSON file edited by hand) cannot make every Validate
+// call expensive. NewValidator fails with a CostError for each such rule.
+//
+// The cost is CEL's static estimate, in the same units as WithCostLimit. The sizes of strings,
+// bytes, lists and maps that the rules see are unknown when the Validator is created, so they
+// are assumed to be up to maxSize; e.g. `self.all(x, x.all(y, y != ""))` costs in proportion to
+// maxSize squared.
+func WithCostBudget(budget, maxSize uint64) ValidatorOption {
+	return func(o *validatorOptions) {
+		o.costBudget = budget
+		o.costMaxSize = maxSize
+	}
+}
+
+// WithCostLimit cancels the evaluation of a rule when its actual cost goes over limit, using
+// CEL's cost tracking. Validate reports the rule with a CostError, along with the other failures.
+func WithCostLimit(limit uint64) ValidatorOption {
+	return func(o *validatorOptions) {
+		o.costLimit = limit
+	}
+}
+
+// WithDefaultCostBudget sets the budget of WithCostBudget for the Validators that use the Engine
+// and do not set their own.
+func WithDefaultCostBudget(budget, maxSize uint64) EngineOption {
+	return func(o *engineOptions) {
+		o.costBudget = budget
+		o.costMaxSize = maxSize
+	}
+}
+
+// WithDefaultCostLimit sets the limit of WithCostLimit for the Validators that use the Engine
+// and do not set their own.
+func WithDefaultCostLimit(limit uint64) EngineOption {
+	return func(o *engineOptions) {
+		o.costLimit = limit
+	}
+}
+
+// checkCost estimates the cost of every rule in the environments that it is evaluated in, for
+// WithCostBudget. A rule that does not compile is an error too, since its cost is unknown.
+func (v *Validator) checkCost(budget, maxSize uint64) error {
+	type estimateKey struct {
+		env  *cel.Env
+		rule string
+	}
+	estimator := sizeEstimator{maxSize: maxSize}
+	estimates := make(map[estimateKey]checker.CostEstimate)
+	var errs []error
+	check := func(env *cel.Env, typeName, fieldName, rule string) {
+		estimate, ok := estimates[estimateKey{env, rule}]
+		if !ok {
+			where := typeName
+			if fieldName != "" {
+				where += "." + fieldName
+			}
+			ast, issues := env.Compile(rule)
+			if issues != nil && issues.Err() != nil {
+				errs = append(errs, fmt.Errorf("rule %q of %s does not compile: %w", rule, where, issues.Err()))
+				return
+			}
+			var err error
+			estimate, err = env.EstimateCost(ast, estimator)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to estimate the cost of rule %q of %s: %w", rule, where, err))
+				return
+			}
+			estimates[estimateKey{env, rule}] = estimate
+		}
+		if estimate.Max > budget {
+			errs = append(errs, &CostError{TypeName: typeName, FieldName: fieldName, Rule: rule, Estimated: true, Cost: estimate.Max, Limit: budget})
+		}
+	}
+
+	index := v.ruleIndex()
+	for _, key := range slices.Sorted(maps.Keys(v.rules)) {
+		envs, ok, err := v.ruleEnvsOf(key, index)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			envs.each(key, v.rules[key], check)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sizeEstimator assumes that every value whose size is unknown has a size of up to maxSize.
+type sizeEstimator struct {
+	maxSize uint64
+}
+
+func (e sizeEstimator) EstimateSize(element checker.AstNode) *checker.SizeEstimate {
+	return &checker.SizeEstimate{Min: 0, Max: e.maxSize}
+}
+
+// EstimateCallCost estimates the cost of matching a regular expression, which CEL assumes to be
+// small: the time to match is proportional to the size of the string times the complexity of the
+// pattern (see regexComplexity), or its size if it is not a constant.
+func (e sizeEstimator) EstimateCallCost(function, overloadID string, target *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	if function != "matches" && function != "custom.matches" {
+		return nil
+	}
+	if target != nil {
+		args = append([]checker.AstNode{*target}, args...)
+	}
+	if len(args) != 2 {
+		return nil
+	}
+	complexity := e.sizeOf(args[1])
+	if pattern := args[1].Expr(); pattern.Kind() == ast.LiteralKind && pattern.AsLiteral().Type() == types.StringType {
+		n, err := regexComplexity(pattern.AsLiteral().Value().(string))
+		if err != nil {
+			return nil // The rule fails to compile.
+		}
+		complexity = uint64(n)
+	}
+	cost := uint64(math.MaxUint64)
+	if size := e.sizeOf(args[0]) + 1; complexity <= math.MaxUint64/size {
+		cost = size * complexity
+	}
+	return &checker.CallEstimate{CostEstimate: checker.CostEstimate{Min: 1, Max: cost}}
+}
+
+// sizeOf returns the maximum size of the value of node.
+func (e sizeEstimator) sizeOf(node checker.AstNode) uint64 {
+	if size := node.ComputedSize(); size != nil {
+		return size.Max
+	}
+	return e.maxSize
+}
+
+// evalError returns the error for a rule whose evaluation failed: a CostError if it was
+// cancelled for going over the cost limit, and otherwise a ValidationError.
+func (v *Validator) evalError(path, typeName, fieldName, rule string, err error) error {
+	var cancelled interpreter.EvalCancelledError
+	if errors.As(err, &cancelled) && cancelled.Cause == interpreter.CostLimitExceeded {
+		return &CostError{TypeName: typeName, FieldName: fieldName, Rule: rule, Path: path, Limit: v.costLimit}
+	}
+	return newValidationErrorAt(path, typeName, fieldName, fmt.Sprintf("evaluation error: %s", err))
+}
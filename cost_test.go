@@ -0,0 +1,181 @@
+package veritas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type costOrder struct {
+	Items []string
+	Note  string
+}
+
+func TestValidator_WithCostBudget(t *testing.T) {
+	const key = "github.com/podhmo/veritas.costOrder"
+	newValidator := func(rules ValidationRuleSet, opts ...ValidatorOption) (*Validator, error) {
+		return NewValidator(append([]ValidatorOption{
+			WithRuleProvider(&mapRuleProvider{rules: map[string]ValidationRuleSet{key: rules}}),
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithTypes(costOrder{}),
+		}, opts...)...)
+	}
+	nested := `self.Items.all(x, self.Items.all(y, x == y || !x.startsWith(y)))`
+
+	t.Run("rules within the budget", func(t *testing.T) {
+		rules := ValidationRuleSet{
+			TypeRules:  []string{`self.Items.size() <= 10`},
+			FieldRules: map[string][]string{"Items": {`self.all(x, x != "")`}, "Note": {`self.size() < 100`}},
+		}
+		if _, err := newValidator(rules, WithCostBudget(10000, 100)); err != nil {
+			t.Errorf("NewValidator() failed: %v", err)
+		}
+	})
+
+	t.Run("rules over the budget", func(t *testing.T) {
+		rules := ValidationRuleSet{
+			TypeRules:  []string{nested},
+			FieldRules: map[string][]string{"Items": {`self.all(x, x != "")`}, "Note": {`self.matches("^(a+)+$")`}},
+		}
+		_, err := newValidator(rules, WithCostBudget(10000, 100))
+		var costErr *CostError
+		if !errors.As(err, &costErr) {
+			t.Fatalf("NewValidator() error = %v, want a CostError", err)
+		}
+		if costErr.TypeName != key || costErr.FieldName != "" || costErr.Rule != nested || !costErr.Estimated || costErr.Cost <= 10000 || costErr.Limit != 10000 {
+			t.Errorf("CostError = %+v", costErr)
+		}
+		if errs, ok := err.(interface{ Unwrap() []error }); !ok || len(errs.Unwrap()) != 1 {
+			t.Errorf("NewValidator() error = %v, want only the nested rule to be rejected", err)
+		}
+		// Without a budget, the rules are accepted.
+		if _, err := newValidator(rules); err != nil {
+			t.Errorf("NewValidator() failed without WithCostBudget: %v", err)
+		}
+	})
+
+	t.Run("regular expressions cost in proportion to the string and the pattern", func(t *testing.T) {
+		short := `self.matches("^(a+)+$")`
+		long := `self.matches("^[a-z]{1,20}@[a-z]{1,20}\\.com$")`
+		dynamic := `custom.matches(self, self)`
+		rules := ValidationRuleSet{
+			FieldRules: map[string][]string{"Note": {short, long, dynamic}},
+		}
+		_, err := newValidator(rules, WithCostBudget(1000, 100))
+		var rejected []string
+		for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+			var costErr *CostError
+			if !errors.As(err, &costErr) {
+				t.Fatalf("NewValidator() error = %v, want CostErrors", err)
+			}
+			rejected = append(rejected, costErr.Rule)
+		}
+		if want := []string{long, dynamic}; !slices.Equal(rejected, want) {
+			t.Errorf("rejected rules = %q, want %q", rejected, want)
+		}
+	})
+
+	t.Run("rules that do not compile", func(t *testing.T) {
+		rules := ValidationRuleSet{
+			FieldRules: map[string][]string{"Note": {`self.size() < "100"`}},
+		}
+		_, err := newValidator(rules, WithCostBudget(10000, 100))
+		want := `rule "self.size() < \"100\"" of ` + key + `.Note does not compile`
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("NewValidator() error = %v, want %q", err, want)
+		}
+	})
+
+	t.Run("the default budget of the engine", func(t *testing.T) {
+		engine, err := NewEngineWithOptions(slog.New(slog.NewTextHandler(io.Discard, nil)), WithDefaultCostBudget(10000, 100))
+		if err != nil {
+			t.Fatalf("NewEngineWithOptions() failed: %v", err)
+		}
+		rules := ValidationRuleSet{TypeRules: []string{nested}}
+		var costErr *CostError
+		if _, err := newValidator(rules, WithEngine(engine)); !errors.As(err, &costErr) {
+			t.Errorf("NewValidator() error = %v, want a CostError", err)
+		}
+		// The budget of the Validator takes precedence.
+		if _, err := newValidator(rules, WithEngine(engine), WithCostBudget(10000000, 100)); err != nil {
+			t.Errorf("NewValidator() with a larger budget failed: %v", err)
+		}
+	})
+}
+
+func TestValidator_WithCostLimit(t *testing.T) {
+	const key = "github.com/podhmo/veritas.costOrder"
+	nested := `self.Items.all(x, self.Items.all(y, x == y || !x.startsWith(y)))`
+	v, err := NewValidator(
+		WithRuleProvider(&mapRuleProvider{rules: map[string]ValidationRuleSet{
+			key: {
+				TypeRules:  []string{nested},
+				FieldRules: map[string][]string{"Note": {`self != ""`}},
+			},
+		}}),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithTypes(costOrder{}),
+		WithCostLimit(1000),
+	)
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+	testCostLimit(t, v)
+}
+
+func TestEngine_WithDefaultCostLimit(t *testing.T) {
+	const key = "github.com/podhmo/veritas.costOrder"
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := NewEngineWithOptions(logger, WithDefaultCostLimit(1000))
+	if err != nil {
+		t.Fatalf("NewEngineWithOptions() failed: %v", err)
+	}
+	v, err := NewValidator(
+		WithEngine(engine),
+		WithRuleProvider(&mapRuleProvider{rules: map[string]ValidationRuleSet{
+			key: {
+				TypeRules:  []string{`self.Items.all(x, self.Items.all(y, x == y || !x.startsWith(y)))`},
+				FieldRules: map[string][]string{"Note": {`self != ""`}},
+			},
+		}}),
+		WithLogger(logger),
+		WithTypes(costOrder{}),
+	)
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+	testCostLimit(t, v)
+}
+
+// testCostLimit checks that v, whose rules are those of TestValidator_WithCostLimit, cancels the
+// nested rule at a cost of 1000.
+func testCostLimit(t *testing.T, v *Validator) {
+	t.Helper()
+	const key = "github.com/podhmo/veritas.costOrder"
+	nested := `self.Items.all(x, self.Items.all(y, x == y || !x.startsWith(y)))`
+
+	if err := v.Validate(context.Background(), costOrder{Items: []string{"a", "b"}, Note: "ok"}); err != nil {
+		t.Errorf("Validate() with few items failed: %v", err)
+	}
+
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = strings.Repeat("x", i+1)
+	}
+	err := v.Validate(context.Background(), costOrder{Items: items})
+	var costErr *CostError
+	if !errors.As(err, &costErr) {
+		t.Fatalf("Validate() error = %v, want a CostError", err)
+	}
+	if costErr.TypeName != key || costErr.Rule != nested || costErr.Estimated || costErr.Limit != 1000 {
+		t.Errorf("CostError = %+v", costErr)
+	}
+	// The other rules are still evaluated.
+	if got := ValidationErrors(err); len(got) != 1 || got[0].FieldName != "Note" {
+		t.Errorf("ValidationErrors() = %v, want the failure of Note", got)
+	}
+}
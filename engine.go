@@ -2,61 +2,309 @@
 package veritas
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"regexp"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
 	lru "github.com/hashicorp/golang-lru/v2"
 )
 
 // Engine is the core component that manages base configurations and program caching.
 // It does not hold a CEL environment itself, but provides the base options to create them.
 type Engine struct {
-	baseOpts     []cel.EnvOption
-	programCache *lru.Cache[string, cel.Program]
-	logger       *slog.Logger
+	baseOpts       []cel.EnvOption
+	programCache   *lru.Cache[programKey, cel.Program]
+	regexCache     *lru.Cache[string, *regexp.Regexp] // Patterns of custom.matches that are not constants.
+	logger         *slog.Logger
+	cacheSize      int
+	regexCacheSize int
+	precompile     bool
+	stats          engineStats
+
+	// The defaults of WithCostBudget and WithCostLimit for the Validators that use the Engine.
+	costBudget, costMaxSize, costLimit uint64
+}
+
+// programKey identifies a compiled program.
+// The same rule compiled against different environments yields different programs
+// (e.g. `self.name` on a Go struct and on a protobuf message), so the environment is part of the key.
+// A program with a cost limit is a different program from the one without.
+type programKey struct {
+	env       *cel.Env
+	rule      string
+	costLimit uint64
+}
+
+// EngineOption is an option for configuring an Engine.
+type EngineOption func(*engineOptions)
+
+type engineOptions struct {
+	funcs          []cel.EnvOption
+	extensions     []Extension
+	cacheSize      int
+	regexCacheSize int
+	precompile     bool
+
+	costBudget, costMaxSize, costLimit uint64
+}
+
+// WithFunctions adds CEL functions (or any other environment options) to the environment of rules.
+func WithFunctions(funcs ...cel.EnvOption) EngineOption {
+	return func(o *engineOptions) {
+		o.funcs = append(o.funcs, funcs...)
+	}
+}
+
+// WithExtensions enables CEL extension libraries for rules.
+func WithExtensions(extensions ...Extension) EngineOption {
+	return func(o *engineOptions) {
+		o.extensions = append(o.extensions, extensions...)
+	}
+}
+
+// WithProgramCacheSize sets the number of compiled programs that the Engine keeps (256 by default).
+// A program is compiled for each rule and environment it is evaluated in, so a cache smaller than
+// that makes rules compile again and again; Stats tells how many programs are evicted.
+func WithProgramCacheSize(size int) EngineOption {
+	return func(o *engineOptions) {
+		o.cacheSize = size
+	}
 }
 
-// NewEngine creates a new validation engine.
+// WithRegexCacheSize sets the number of compiled regular expressions that the Engine keeps for
+// `custom.matches` (128 by default). Only patterns that are not constants go through this cache,
+// e.g. `custom.matches(self.value, self.pattern)`; constant patterns are compiled with their program.
+func WithRegexCacheSize(size int) EngineOption {
+	return func(o *engineOptions) {
+		o.regexCacheSize = size
+	}
+}
+
+// WithPrecompile makes the Validators that use the Engine compile all of their rules when they
+// are created, so that a rule that does not compile makes NewValidator fail, and the first
+// validations do not pay for compiling rules. Rules are compiled in the environments that they are
+// evaluated in; rules of keys that belong to no known type are never evaluated, so they are skipped.
+// The program cache should be large enough for all of them.
+func WithPrecompile() EngineOption {
+	return func(o *engineOptions) {
+		o.precompile = true
+	}
+}
+
+// Extension is the name of a CEL extension library.
+type Extension string
+
+const (
+	ExtensionStrings              Extension = "strings"                // e.g. "a,b".split(","), "abc".indexOf("b")
+	ExtensionLists                Extension = "lists"                  // e.g. [3, 1].sort(), lists.range(3)
+	ExtensionSets                 Extension = "sets"                   // e.g. sets.contains([1, 2], [1])
+	ExtensionMath                 Extension = "math"                   // e.g. math.greatest(1, 2)
+	ExtensionEncoders             Extension = "encoders"               // e.g. base64.decode("aGk=")
+	ExtensionRegex                Extension = "regex"                  // e.g. regex.extract("ab", "a(.)")
+	ExtensionBindings             Extension = "bindings"               // e.g. cel.bind(x, self.size(), x > 0)
+	ExtensionTwoVarComprehensions Extension = "two-var-comprehensions" // e.g. self.all(k, v, v != "")
+)
+
+// envOption returns the environment option that enables the extension.
+func (x Extension) envOption() (cel.EnvOption, error) {
+	switch x {
+	case ExtensionStrings:
+		return ext.Strings(), nil
+	case ExtensionLists:
+		return ext.Lists(), nil
+	case ExtensionSets:
+		return ext.Sets(), nil
+	case ExtensionMath:
+		return ext.Math(), nil
+	case ExtensionEncoders:
+		return ext.Encoders(), nil
+	case ExtensionRegex:
+		return ext.Regex(), nil
+	case ExtensionBindings:
+		return ext.Bindings(), nil
+	case ExtensionTwoVarComprehensions:
+		return ext.TwoVarComprehensions(), nil
+	}
+	return nil, fmt.Errorf("unknown CEL extension %q", string(x))
+}
+
+// NewEngine creates a new validation engine, with funcs added to the environment of rules.
+// It is a shorthand for NewEngineWithOptions with WithFunctions.
 func NewEngine(logger *slog.Logger, funcs ...cel.EnvOption) (*Engine, error) {
-	// Add support for common CEL features.
-	opts := DefaultEnvOptions()
-	opts = append(opts, funcs...)
-	opts = append(opts, cel.HomogeneousAggregateLiterals())
+	return NewEngineWithOptions(logger, WithFunctions(funcs...))
+}
+
+// NewEngineWithOptions creates a new validation engine with the given options.
+// `custom.matches` compiles the patterns that are not constants through a cache of the Engine,
+// shared by the Validators that use it (see WithRegexCacheSize).
+func NewEngineWithOptions(logger *slog.Logger, opts ...EngineOption) (*Engine, error) {
+	options := &engineOptions{cacheSize: 256, regexCacheSize: 128}
+	for _, opt := range opts {
+		opt(options)
+	}
 
-	cache, err := lru.New[string, cel.Program](256)
+	e := &Engine{
+		logger:         logger,
+		cacheSize:      options.cacheSize,
+		regexCacheSize: options.regexCacheSize,
+		precompile:     options.precompile,
+		costBudget:     options.costBudget,
+		costMaxSize:    options.costMaxSize,
+		costLimit:      options.costLimit,
+	}
+	cache, err := lru.NewWithEvict[programKey, cel.Program](options.cacheSize, func(programKey, cel.Program) {
+		e.stats.evictions.Add(1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	regexCache, err := lru.NewWithEvict[string, *regexp.Regexp](options.regexCacheSize, func(string, *regexp.Regexp) {
+		e.stats.regexEvictions.Add(1)
+	})
 	if err != nil {
 		return nil, err
 	}
+	e.programCache = cache
+	e.regexCache = regexCache
+
+	// Add support for common CEL features.
+	baseOpts := defaultEnvOptions(e.compileRegex)
+	for _, x := range options.extensions {
+		opt, err := x.envOption()
+		if err != nil {
+			return nil, err
+		}
+		baseOpts = append(baseOpts, opt)
+	}
+	baseOpts = append(baseOpts, options.funcs...)
+	baseOpts = append(baseOpts, cel.HomogeneousAggregateLiterals())
+	e.baseOpts = baseOpts
+	return e, nil
+}
+
+// EngineStats are the statistics of an Engine, since it was created.
+type EngineStats struct {
+	// CacheSize is the number of programs that the cache can hold, and Programs the number it holds.
+	CacheSize int
+	Programs  int
+
+	// Hits and Misses count the lookups of programs in the cache, and Evictions the programs
+	// that were removed from it to make room for others.
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+
+	// CompileTime is the total time spent compiling programs, on misses.
+	CompileTime time.Duration
+
+	// Evals is the number of evaluations of rules.
+	Evals uint64
+
+	// RegexCacheSize, Regexes, RegexHits, RegexMisses and RegexEvictions are the same for the
+	// cache of the patterns of `custom.matches` that are not constants.
+	RegexCacheSize int
+	Regexes        int
+	RegexHits      uint64
+	RegexMisses    uint64
+	RegexEvictions uint64
+}
+
+// engineStats are the counters of EngineStats, which are updated concurrently.
+type engineStats struct {
+	hits, misses, evictions atomic.Uint64
+	compileTime             atomic.Int64 // In nanoseconds.
+	evals                   atomic.Uint64
+
+	regexHits, regexMisses, regexEvictions atomic.Uint64
+}
+
+// Stats returns the statistics of the Engine, e.g. to size its program cache.
+func (e *Engine) Stats() EngineStats {
+	return EngineStats{
+		CacheSize:   e.cacheSize,
+		Programs:    e.programCache.Len(),
+		Hits:        e.stats.hits.Load(),
+		Misses:      e.stats.misses.Load(),
+		Evictions:   e.stats.evictions.Load(),
+		CompileTime: time.Duration(e.stats.compileTime.Load()),
+		Evals:       e.stats.evals.Load(),
+
+		RegexCacheSize: e.regexCacheSize,
+		Regexes:        e.regexCache.Len(),
+		RegexHits:      e.stats.regexHits.Load(),
+		RegexMisses:    e.stats.regexMisses.Load(),
+		RegexEvictions: e.stats.regexEvictions.Load(),
+	}
+}
 
-	return &Engine{
-		baseOpts:     opts,
-		programCache: cache,
-		logger:       logger,
-	}, nil
+// compileRegex compiles pattern, or returns it from the cache if it has been compiled before.
+func (e *Engine) compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := e.regexCache.Get(pattern); ok {
+		e.stats.regexHits.Add(1)
+		return re, nil
+	}
+	e.stats.regexMisses.Add(1)
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	e.regexCache.Add(pattern, re)
+	return re, nil
 }
 
 // getProgram compiles a CEL expression against a given environment and returns a usable program.
-// It uses an LRU cache to avoid re-compiling frequently used expressions.
-// The cache key is just the rule string, implying that rules are environment-agnostic enough
-// for this library's use case, which might be a simplifying assumption.
-func (e *Engine) getProgram(env *cel.Env, rule string) (cel.Program, error) {
-	if prog, ok := e.programCache.Get(rule); ok {
+// It uses an LRU cache, keyed by environment and rule, to avoid re-compiling frequently used expressions.
+// If costLimit is not 0, the evaluation of the program is cancelled when its actual cost goes over it.
+func (e *Engine) getProgram(env *cel.Env, rule string, costLimit uint64) (cel.Program, error) {
+	key := programKey{env: env, rule: rule, costLimit: costLimit}
+	if prog, ok := e.programCache.Get(key); ok {
 		e.logger.Debug("cache hit", "rule", rule)
+		e.stats.hits.Add(1)
 		return prog, nil
 	}
 
 	e.logger.Debug("cache miss", "rule", rule)
+	e.stats.misses.Add(1)
+	start := time.Now()
+	defer func() { e.stats.compileTime.Add(int64(time.Since(start))) }()
 
 	ast, issues := env.Compile(rule)
 	if issues != nil && issues.Err() != nil {
 		return nil, issues.Err()
 	}
 
-	prog, err := env.Program(ast)
+	progOpts := []cel.ProgramOption{cel.OptimizeRegex(regexOptimizations...)}
+	if costLimit != 0 {
+		progOpts = append(progOpts, cel.CostLimit(costLimit))
+	}
+	prog, err := env.Program(ast, progOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	e.programCache.Add(rule, prog)
+	prog = &countedProgram{Program: prog, evals: &e.stats.evals}
+	e.programCache.Add(key, prog)
 	return prog, nil
 }
+
+// countedProgram counts the evaluations of a program.
+type countedProgram struct {
+	cel.Program
+	evals *atomic.Uint64
+}
+
+func (p *countedProgram) Eval(input any) (ref.Val, *cel.EvalDetails, error) {
+	p.evals.Add(1)
+	return p.Program.Eval(input)
+}
+
+func (p *countedProgram) ContextEval(ctx context.Context, input any) (ref.Val, *cel.EvalDetails, error) {
+	p.evals.Add(1)
+	return p.Program.ContextEval(ctx, input)
+}
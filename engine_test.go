@@ -2,7 +2,10 @@ package veritas
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/google/cel-go/cel"
@@ -52,14 +55,202 @@ func TestEngine_getProgram_Caching(t *testing.T) {
 	rule := `1 < 2`
 
 	// Cache miss
-	_, err = engine.getProgram(env, rule)
+	_, err = engine.getProgram(env, rule, 0)
 	if err != nil {
 		t.Fatalf("getProgram() first call failed: %v", err)
 	}
 
 	// Cache hit
-	_, err = engine.getProgram(env, rule)
+	_, err = engine.getProgram(env, rule, 0)
 	if err != nil {
 		t.Fatalf("getProgram() second call failed: %v", err)
 	}
 }
+
+func TestEngine_getProgram_Regex(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewEngine(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	env, err := cel.NewEnv(append(engine.baseOpts, cel.Variable("self", cel.StringType), cel.Variable("pattern", cel.StringType))...)
+	if err != nil {
+		t.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+
+	t.Run("invalid constant patterns are compilation errors", func(t *testing.T) {
+		for _, rule := range []string{`self.matches('[')`, `custom.matches(self, '[')`} {
+			if _, err := engine.getProgram(env, rule, 0); err == nil || !strings.Contains(err.Error(), "missing closing ]") {
+				t.Errorf("getProgram(%q) error = %v, want an invalid pattern", rule, err)
+			}
+		}
+	})
+
+	t.Run("patterns that are not constants are cached", func(t *testing.T) {
+		prog, err := engine.getProgram(env, `custom.matches(self, pattern)`, 0)
+		if err != nil {
+			t.Fatalf("getProgram() failed: %v", err)
+		}
+		for _, s := range []string{"abc", "123", "abc"} {
+			out, _, err := prog.Eval(map[string]any{"self": s, "pattern": "^[a-z]+$"})
+			if err != nil {
+				t.Fatalf("Eval() failed: %v", err)
+			}
+			if got, want := out.Value(), s == "abc"; got != want {
+				t.Errorf("Eval(%q) = %v, want %v", s, got, want)
+			}
+		}
+		if !engine.regexCache.Contains("^[a-z]+$") || engine.regexCache.Len() != 1 {
+			t.Errorf("regexCache.Keys() = %q, want the pattern", engine.regexCache.Keys())
+		}
+
+		// An invalid pattern is still an evaluation error.
+		if _, _, err := prog.Eval(map[string]any{"self": "abc", "pattern": "["}); err == nil {
+			t.Error("Eval() with an invalid pattern succeeded")
+		}
+	})
+}
+
+func TestNewEngineWithOptions(t *testing.T) {
+	t.Parallel()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	t.Run("extensions", func(t *testing.T) {
+		engine, err := NewEngineWithOptions(logger, WithExtensions(ExtensionStrings, ExtensionLists))
+		if err != nil {
+			t.Fatalf("NewEngineWithOptions() failed: %v", err)
+		}
+		env, err := cel.NewEnv(append(engine.baseOpts, cel.Variable("self", cel.StringType))...)
+		if err != nil {
+			t.Fatalf("cel.NewEnv() failed: %v", err)
+		}
+		prog, err := engine.getProgram(env, `self.split(",").distinct().size() == 2`, 0)
+		if err != nil {
+			t.Fatalf("getProgram() failed: %v", err)
+		}
+		if out, _, err := prog.Eval(map[string]any{"self": "a,b,a"}); err != nil || out.Value() != true {
+			t.Errorf("Eval() = %v, %v, want true", out, err)
+		}
+
+		if _, err := NewEngineWithOptions(logger, WithExtensions("unknown")); err == nil || !strings.Contains(err.Error(), `unknown CEL extension "unknown"`) {
+			t.Errorf("NewEngineWithOptions() error = %v, want an unknown extension", err)
+		}
+	})
+
+	t.Run("cache size and stats", func(t *testing.T) {
+		engine, err := NewEngineWithOptions(logger, WithProgramCacheSize(2))
+		if err != nil {
+			t.Fatalf("NewEngineWithOptions() failed: %v", err)
+		}
+		env, err := cel.NewEnv(engine.baseOpts...)
+		if err != nil {
+			t.Fatalf("cel.NewEnv() failed: %v", err)
+		}
+		for _, rule := range []string{`1 < 2`, `2 < 3`, `1 < 2`, `3 < 4`, `2 < 3`} {
+			prog, err := engine.getProgram(env, rule, 0)
+			if err != nil {
+				t.Fatalf("getProgram() failed: %v", err)
+			}
+			if _, _, err := prog.ContextEval(context.Background(), map[string]any{}); err != nil {
+				t.Fatalf("ContextEval() failed: %v", err)
+			}
+		}
+
+		stats := engine.Stats()
+		// `2 < 3` is evicted by `3 < 4`, as `1 < 2` was used more recently.
+		want := EngineStats{CacheSize: 2, Programs: 2, Hits: 1, Misses: 4, Evictions: 2, Evals: 5, RegexCacheSize: 128}
+		if stats.CompileTime <= 0 {
+			t.Errorf("Stats().CompileTime = %v, want the time of the misses", stats.CompileTime)
+		}
+		stats.CompileTime = 0
+		if stats != want {
+			t.Errorf("Stats() = %+v, want %+v", stats, want)
+		}
+	})
+
+	t.Run("regex cache size and stats", func(t *testing.T) {
+		engine, err := NewEngineWithOptions(logger, WithRegexCacheSize(1))
+		if err != nil {
+			t.Fatalf("NewEngineWithOptions() failed: %v", err)
+		}
+		env, err := cel.NewEnv(append(engine.baseOpts, cel.Variable("self", cel.StringType), cel.Variable("pattern", cel.StringType))...)
+		if err != nil {
+			t.Fatalf("cel.NewEnv() failed: %v", err)
+		}
+		prog, err := engine.getProgram(env, `custom.matches(self, pattern)`, 0)
+		if err != nil {
+			t.Fatalf("getProgram() failed: %v", err)
+		}
+		for _, pattern := range []string{"^a", "^a", "^b", "^a"} {
+			if _, _, err := prog.Eval(map[string]any{"self": "abc", "pattern": pattern}); err != nil {
+				t.Fatalf("Eval() failed: %v", err)
+			}
+		}
+
+		stats := engine.Stats()
+		if stats.RegexCacheSize != 1 || stats.Regexes != 1 || stats.RegexHits != 1 || stats.RegexMisses != 3 || stats.RegexEvictions != 2 {
+			t.Errorf("Stats() = %+v, want 1 hit, 3 misses and 2 evictions of regular expressions", stats)
+		}
+	})
+}
+
+// precompilePercent is a named scalar type, whose rules apply to fields of that type.
+type precompilePercent int
+
+type precompileOrder struct {
+	Items    []string
+	Note     string
+	Discount precompilePercent
+}
+
+func TestEngine_WithPrecompile(t *testing.T) {
+	t.Parallel()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	const pkg = "github.com/podhmo/veritas."
+	newValidator := func(rules map[string]ValidationRuleSet) (*Validator, *Engine, error) {
+		engine, err := NewEngineWithOptions(logger, WithPrecompile())
+		if err != nil {
+			t.Fatalf("NewEngineWithOptions() failed: %v", err)
+		}
+		v, err := NewValidator(
+			WithEngine(engine),
+			WithRuleProvider(&mapRuleProvider{rules: rules}),
+			WithLogger(logger),
+			WithTypes(precompileOrder{}),
+		)
+		return v, engine, err
+	}
+
+	v, engine, err := newValidator(map[string]ValidationRuleSet{
+		pkg + "precompileOrder": {
+			TypeRules:  []string{`self.Items.size() <= 10`},
+			FieldRules: map[string][]string{"Items": {`self.all(x, x != "")`}, "Note": {`self.size() < 100`}},
+		},
+		pkg + "precompilePercent": {TypeRules: []string{`self >= 0 && self <= 100`}},
+		// Rules that are never evaluated are not compiled: a struct that is not registered,
+		// and a misspelled named type.
+		pkg + "costOrder":        {TypeRules: []string{`self.Items.size() <= 20`}},
+		pkg + "precompilePercnt": {TypeRules: []string{`self >= 1`}},
+	})
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+	if got := engine.Stats(); got.Misses != 4 || got.Programs != 4 {
+		t.Errorf("Stats() after NewValidator = %+v, want 4 compiled programs", got)
+	}
+	if err := v.Validate(context.Background(), precompileOrder{Items: []string{"a"}, Discount: 10}); err != nil {
+		t.Errorf("Validate() failed: %v", err)
+	}
+	if got := engine.Stats(); got.Misses != 4 || got.Evals != 4 {
+		t.Errorf("Stats() after Validate = %+v, want no compilation and 4 evaluations", got)
+	}
+
+	_, _, err = newValidator(map[string]ValidationRuleSet{
+		pkg + "precompileOrder": {FieldRules: map[string][]string{"Note": {`self.matches('[')`}}},
+	})
+	want := `rule "self.matches('[')" of ` + pkg + `precompileOrder.Note does not compile`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("NewValidator() error = %v, want %q", err, want)
+	}
+}
@@ -1,6 +1,7 @@
 package veritas
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -9,13 +10,36 @@ type ValidationError struct {
 	TypeName  string
 	FieldName string
 	Rule      string
+
+	// Path is the location of the failing value, relative to the validated object
+	// (e.g. "Profiles[0].Handle"). It is made of Go field names, and of proto field names
+	// inside protobuf messages (e.g. "Payment.amount"). It is empty for the rules of the
+	// validated object's type.
+	Path string
+
+	// Err is the underlying error returned by a Validatable hook, if any.
+	Err error
 }
 
 func (e *ValidationError) Error() string {
-	if e.FieldName == "" {
-		return fmt.Sprintf("%s: validation failed, rule: %s", e.TypeName, e.Rule)
+	where := e.TypeName
+	if e.FieldName != "" {
+		where += "." + e.FieldName
+	}
+	if where == "" {
+		where = e.Path
+	}
+	msg := fmt.Sprintf("%s: validation failed, rule: %s", where, e.Rule)
+	// The path only adds to the type's name for values nested in the validated object.
+	if e.Path != "" && e.Path != e.FieldName && e.Path != where {
+		msg += fmt.Sprintf(" (at %s)", e.Path)
 	}
-	return fmt.Sprintf("%s.%s: validation failed, rule: %s", e.TypeName, e.FieldName, e.Rule)
+	return msg
+}
+
+// Unwrap returns the underlying error returned by a Validatable hook, if any.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
 }
 
 // NewValidationError creates a new validation error.
@@ -27,6 +51,39 @@ func NewValidationError(typeName, fieldName, rule string) error {
 	}
 }
 
+// CostError reports a rule that costs more than allowed: either its estimated worst-case cost
+// is over the budget set with WithCostBudget, which fails NewValidator, or its evaluation went
+// over the limit set with WithCostLimit and was cancelled, which fails Validate.
+type CostError struct {
+	TypeName  string
+	FieldName string
+	Rule      string
+
+	// Path is the location of the value being validated, as in ValidationError.
+	Path string
+
+	// Estimated is true for a rule rejected by its estimated cost, and false for a cancelled evaluation.
+	Estimated bool
+	// Cost is the estimated worst-case cost of the rule, if Estimated.
+	Cost uint64
+	// Limit is the budget or the limit that the rule went over.
+	Limit uint64
+}
+
+func (e *CostError) Error() string {
+	where := e.Path
+	if where == "" {
+		where = e.TypeName
+		if e.FieldName != "" {
+			where += "." + e.FieldName
+		}
+	}
+	if e.Estimated {
+		return fmt.Sprintf("%s: rule %s has an estimated cost of %d, over the budget of %d", where, e.Rule, e.Cost, e.Limit)
+	}
+	return fmt.Sprintf("%s: evaluation of rule %s was cancelled, as its cost went over the limit of %d", where, e.Rule, e.Limit)
+}
+
 // FatalError represents a critical, non-recoverable error during validation,
 // such as a rule compilation failure.
 type FatalError struct {
@@ -42,6 +99,23 @@ func NewFatalError(message string) error {
 	return &FatalError{Message: message}
 }
 
+// ValidationErrors returns all ValidationErrors contained in err,
+// flattening errors joined with errors.Join.
+func ValidationErrors(err error) []*ValidationError {
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		var result []*ValidationError
+		for _, e := range errs.Unwrap() {
+			result = append(result, ValidationErrors(e)...)
+		}
+		return result
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return []*ValidationError{ve}
+	}
+	return nil
+}
+
 // ToErrorMap converts a validation error into a map of field names to error messages.
 // If the error is not a composition of ValidationErrors, it returns nil.
 func ToErrorMap(err error) map[string]string {
@@ -1,6 +1,6 @@
 package main
 
-//go:generate go run github.com/podhmo/veritas/cmd/veritas -inject=main.go .
+//go:generate go run github.com/podhmo/veritas/cmd/veritas -strict -inject=main.go .
 
 import (
 	"context"
@@ -1,6 +1,6 @@
 package def
 
-//go:generate go run ../../../cmd/veritas -o ../validation/validator.go .
+//go:generate go run ../../../cmd/veritas -strict -o ../validation/validator.go .
 
 // veritas:
 type User struct {
@@ -11,6 +11,7 @@ import (
 	"os"
 
 	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/httpx"
 )
 
 //go:embed rules.json
@@ -43,42 +44,7 @@ func run(ctx context.Context) error {
 
 	// setup routes
 	slog.InfoContext(ctx, "setup routes")
-	mux := http.NewServeMux()
-	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-
-		// decode request
-		slog.DebugContext(ctx, "decode request")
-		var user User
-		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		// validate
-		slog.DebugContext(ctx, "validate request", "user", user)
-		if err := v.Validate(ctx, user); err != nil {
-			slog.InfoContext(ctx, "validation failed", "err", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			response := map[string]any{
-				"message": "validation failed",
-				"details": veritas.ToErrorMap(err),
-			}
-			if err := json.NewEncoder(w).Encode(response); err != nil {
-				slog.ErrorContext(ctx, "failed to encode error response", "err", err)
-			}
-			return
-		}
-
-		// write response
-		slog.DebugContext(ctx, "write response")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		if err := json.NewEncoder(w).Encode(user); err != nil {
-			slog.ErrorContext(ctx, "failed to encode success response", "err", err)
-		}
-	})
+	mux := newMux(v)
 
 	// start server
 	slog.InfoContext(ctx, "start server", "port", ":8080")
@@ -99,3 +65,31 @@ func run(ctx context.Context) error {
 	slog.InfoContext(ctx, "shutting down server")
 	return server.Shutdown(context.Background())
 }
+
+func newMux(v *veritas.Validator) *http.ServeMux {
+	handle := httpx.Middleware(v)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /users", handle(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		// decode and validate request
+		// failures are rendered as application/problem+json by the middleware
+		slog.DebugContext(ctx, "decode and validate request")
+		user, err := httpx.DecodeAndValidate[User](r)
+		if err != nil {
+			slog.InfoContext(ctx, "invalid request", "err", err)
+			return err
+		}
+
+		// write response
+		slog.DebugContext(ctx, "write response")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(user); err != nil {
+			slog.ErrorContext(ctx, "failed to encode success response", "err", err)
+		}
+		return nil
+	}))
+	return mux
+}
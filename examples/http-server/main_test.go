@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/httpx"
 )
 
 func TestUserAPI(t *testing.T) {
@@ -21,29 +22,7 @@ func TestUserAPI(t *testing.T) {
 	}
 
 	// setup mux
-	mux := http.NewServeMux()
-	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
-		var user User
-		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		if err := v.Validate(r.Context(), user); err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			response := map[string]any{
-				"message": "validation failed",
-				"details": veritas.ToErrorMap(err),
-			}
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(user)
-	})
+	mux := newMux(v)
 
 	// setup server
 	server := httptest.NewServer(mux)
@@ -55,6 +34,7 @@ func TestUserAPI(t *testing.T) {
 		body           string
 		wantStatusCode int
 		wantBody       string
+		wantParams     map[string]string
 	}{
 		{
 			name:           "success",
@@ -66,19 +46,19 @@ func TestUserAPI(t *testing.T) {
 			name:           "validation error - name required",
 			body:           `{"name": "", "email": "test@example.com"}`,
 			wantStatusCode: http.StatusBadRequest,
-			wantBody:       `"details":{"Name":"size(self) > 0"}`,
+			wantParams:     map[string]string{"name": "size(self) > 0"},
 		},
 		{
 			name:           "validation error - email invalid",
 			body:           `{"name": "test", "email": "invalid-email"}`,
 			wantStatusCode: http.StatusBadRequest,
-			wantBody:       `"details":{"Email":"self.contains('@')"}`,
+			wantParams:     map[string]string{"email": "self.contains('@')"},
 		},
 		{
 			name:           "validation error - both invalid",
 			body:           `{"name": "", "email": "invalid-email"}`,
 			wantStatusCode: http.StatusBadRequest,
-			wantBody:       `"details":{"Email":"self.contains('@')","Name":"size(self) > 0"}`,
+			wantParams:     map[string]string{"email": "self.contains('@')", "name": "size(self) > 0"},
 		},
 	}
 
@@ -107,26 +87,26 @@ func TestUserAPI(t *testing.T) {
 			}
 
 			if tt.wantStatusCode == http.StatusBadRequest {
-				details, ok := body["details"].(map[string]any)
+				if got := resp.Header.Get("Content-Type"); got != httpx.ContentTypeProblemJSON {
+					t.Errorf("unexpected content type: got %v, want %v", got, httpx.ContentTypeProblemJSON)
+				}
+				params, ok := body["invalid-params"].([]any)
 				if !ok {
-					t.Fatalf("details is not a map[string]any")
+					t.Fatalf("invalid-params is not a []any")
 				}
-				if tt.name == "validation error - both invalid" {
-					if details["Name"] != "size(self) > 0" {
-						t.Errorf("unexpected error for Name: got %v, want %v", details["Name"], "size(self) > 0")
-					}
-					if details["Email"] != "self.contains('@')" {
-						t.Errorf("unexpected error for Email: got %v, want %v", details["Email"], "self.contains('@')")
-					}
-				} else if tt.name == "validation error - name required" {
-					if details["Name"] != "size(self) > 0" {
-						t.Errorf("unexpected error for Name: got %v, want %v", details["Name"], "size(self) > 0")
-					}
-				} else if tt.name == "validation error - email invalid" {
-					if details["Email"] != "self.contains('@')" {
-						t.Errorf("unexpected error for Email: got %v, want %v", details["Email"], "self.contains('@')")
+				gotParams := make(map[string]string, len(params))
+				for _, p := range params {
+					p := p.(map[string]any)
+					gotParams[p["name"].(string)] = p["reason"].(string)
+				}
+				for name, reason := range tt.wantParams {
+					if gotParams[name] != reason {
+						t.Errorf("unexpected error for %s: got %v, want %v", name, gotParams[name], reason)
 					}
 				}
+				if len(gotParams) != len(tt.wantParams) {
+					t.Errorf("unexpected number of invalid params: got %v, want %v", gotParams, tt.wantParams)
+				}
 			} else {
 				var wantBody map[string]any
 				if err := json.Unmarshal([]byte(tt.wantBody), &wantBody); err != nil {
@@ -1,16 +1,23 @@
 package veritas
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
 )
 
 // DefaultFunctions returns a list of common, useful custom functions for CEL.
 func DefaultFunctions() []cel.EnvOption {
+	return defaultFunctions(regexp.Compile)
+}
+
+// defaultFunctions returns DefaultFunctions, with `custom.matches` compiling patterns with compile.
+func defaultFunctions(compile func(pattern string) (*regexp.Regexp, error)) []cel.EnvOption {
 	return []cel.EnvOption{
 		cel.Function("strings.ToUpper",
 			cel.Overload("upper_string",
@@ -21,24 +28,62 @@ func DefaultFunctions() []cel.EnvOption {
 				}),
 			),
 		),
-		cel.Function("custom.matches",
-			cel.Overload("matches_string_pattern",
-				[]*cel.Type{cel.StringType, cel.StringType},
-				cel.BoolType,
-				cel.BinaryBinding(func(s, p ref.Val) ref.Val {
-					str := s.(types.String).Value().(string)
-					pattern := p.(types.String).Value().(string)
-					matched, err := regexp.MatchString(pattern, str)
-					if err != nil {
-						return types.NewErr("regexp compilation error: %s", err)
-					}
-					return types.Bool(matched)
-				}),
-			),
-		),
+		matchesFunction(compile),
 	}
 }
 
+// matchesFunction declares `custom.matches(string, pattern)`, which compiles pattern with compile.
+func matchesFunction(compile func(pattern string) (*regexp.Regexp, error)) cel.EnvOption {
+	return cel.Function("custom.matches",
+		cel.Overload("matches_string_pattern",
+			[]*cel.Type{cel.StringType, cel.StringType},
+			cel.BoolType,
+			cel.BinaryBinding(func(s, p ref.Val) ref.Val {
+				str := s.(types.String).Value().(string)
+				pattern := p.(types.String).Value().(string)
+				re, err := compile(pattern)
+				if err != nil {
+					return types.NewErr("regexp compilation error: %s", err)
+				}
+				return types.Bool(re.MatchString(str))
+			}),
+		),
+	)
+}
+
+// regexOptimizations compile the constant patterns of `matches` and `custom.matches` once, when
+// a program is built, rather than on every evaluation. An invalid constant pattern is then an
+// error of building the program, as is a rule that does not compile.
+var regexOptimizations = []*interpreter.RegexOptimization{
+	interpreter.MatchesRegexOptimization,
+	{
+		Function:   "custom.matches",
+		OverloadID: "matches_string_pattern",
+		RegexIndex: 1,
+		Factory: func(call interpreter.InterpretableCall, pattern string) (interpreter.InterpretableCall, error) {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("regexp compilation error: %w", err)
+			}
+			return interpreter.NewCall(call.ID(), call.Function(), call.OverloadID(), call.Args(), func(values ...ref.Val) ref.Val {
+				if len(values) != 2 {
+					return types.NoSuchOverloadErr()
+				}
+				str, ok := values[0].Value().(string)
+				if !ok {
+					return types.NoSuchOverloadErr()
+				}
+				return types.Bool(re.MatchString(str))
+			}), nil
+		},
+	},
+}
+
 func DefaultEnvOptions() []cel.EnvOption {
-	return append(DefaultFunctions(), cel.StdLib())
+	return defaultEnvOptions(regexp.Compile)
+}
+
+// defaultEnvOptions returns DefaultEnvOptions, with `custom.matches` compiling patterns with compile.
+func defaultEnvOptions(compile func(pattern string) (*regexp.Regexp, error)) []cel.EnvOption {
+	return append(defaultFunctions(compile), cel.StdLib())
 }
@@ -2,6 +2,7 @@ package veritas
 
 import (
 	"bytes"
+	"io"
 	"log/slog"
 	"testing"
 
@@ -102,3 +103,77 @@ func TestCustomFunctions(t *testing.T) {
 		})
 	}
 }
+
+// The rule of the "email" shorthand, as generated for a string field; TestEmailRule checks
+// that it is the same as the parser's.
+const emailRule = `self.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$')`
+
+// EmailRule exports emailRule to TestEmailRule, which is in package veritas_test, since the
+// parser imports veritas.
+const EmailRule = emailRule
+
+func BenchmarkMatches(b *testing.B) {
+	engine, err := NewEngine(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	if err != nil {
+		b.Fatalf("NewEngine() failed: %v", err)
+	}
+	env, err := cel.NewEnv(append(engine.baseOpts, cel.Variable("self", cel.StringType), cel.Variable("pattern", cel.StringType))...)
+	if err != nil {
+		b.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+	// A program built without the regex optimizations, which compiles the pattern on every evaluation.
+	unoptimized := func(b *testing.B, env *cel.Env, rule string) cel.Program {
+		ast, issues := env.Compile(rule)
+		if issues != nil && issues.Err() != nil {
+			b.Fatalf("Compile() failed: %v", issues.Err())
+		}
+		prog, err := env.Program(ast)
+		if err != nil {
+			b.Fatalf("Program() failed: %v", err)
+		}
+		return prog
+	}
+	optimized := func(b *testing.B, env *cel.Env, rule string) cel.Program {
+		prog, err := engine.getProgram(env, rule, 0)
+		if err != nil {
+			b.Fatalf("getProgram() failed: %v", err)
+		}
+		return prog
+	}
+	defaultEnv, err := cel.NewEnv(append(DefaultEnvOptions(), cel.Variable("self", cel.StringType), cel.Variable("pattern", cel.StringType))...)
+	if err != nil {
+		b.Fatalf("cel.NewEnv() failed: %v", err)
+	}
+
+	vars := map[string]any{"self": "gopher@example.com", "pattern": `^[^@]+@[^@]+$`}
+	benchmarks := []struct {
+		name string
+		prog func(b *testing.B) cel.Program
+	}{
+		{"email/compiled per evaluation", func(b *testing.B) cel.Program { return unoptimized(b, env, emailRule) }},
+		{"email/precompiled", func(b *testing.B) cel.Program { return optimized(b, env, emailRule) }},
+		{"custom.matches constant/compiled per evaluation", func(b *testing.B) cel.Program {
+			return unoptimized(b, defaultEnv, `custom.matches(self, '^[^@]+@[^@]+$')`)
+		}},
+		{"custom.matches constant/precompiled", func(b *testing.B) cel.Program {
+			return optimized(b, env, `custom.matches(self, '^[^@]+@[^@]+$')`)
+		}},
+		{"custom.matches variable/compiled per evaluation", func(b *testing.B) cel.Program {
+			return unoptimized(b, defaultEnv, `custom.matches(self, pattern)`)
+		}},
+		{"custom.matches variable/cached", func(b *testing.B) cel.Program { return optimized(b, env, `custom.matches(self, pattern)`) }},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			prog := bm.prog(b)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				out, _, err := prog.Eval(vars)
+				if err != nil || out.Value() != true {
+					b.Fatalf("Eval() = %v, %v", out, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package veritas
+
+import (
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// splitGenericKey splits a rule key such as "pkg.Box[T]" into its base name ("pkg.Box")
+// and its bracketed type arguments ("T"). ok is false if the key has no type arguments.
+func splitGenericKey(key string) (base, args string, ok bool) {
+	i := strings.Index(key, "[")
+	if i == -1 || !strings.HasSuffix(key, "]") {
+		return key, "", false
+	}
+	return key[:i], key[i+1 : len(key)-1], true
+}
+
+// isGenericKey reports whether key names a generic type by its type parameters (e.g. "pkg.Box[T]"),
+// rather than an instantiation (e.g. "pkg.Box[string]" or "pkg.Box[*example.com/p.Item]").
+// Type parameters are plain identifiers, while the type arguments of an instantiation are
+// predeclared types or qualified by their package path, as in reflect.Type.Name.
+func isGenericKey(key string) bool {
+	_, args, ok := splitGenericKey(key)
+	if !ok {
+		return false
+	}
+	for _, arg := range strings.Split(args, ",") {
+		arg = strings.TrimSpace(arg)
+		if !token.IsIdentifier(arg) || types.Universe.Lookup(arg) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// genericIndex maps the base name of each generic rule key to the key,
+// e.g. "pkg.Box" to "pkg.Box[T]".
+func genericIndex(rules map[string]ValidationRuleSet) map[string]string {
+	index := make(map[string]string)
+	for key := range rules {
+		if isGenericKey(key) {
+			base, _, _ := splitGenericKey(key)
+			index[base] = key
+		}
+	}
+	return index
+}
@@ -0,0 +1,140 @@
+// Package grpcx maps veritas validation errors to gRPC statuses carrying
+// google.rpc.BadRequest details, and provides server interceptors that validate incoming messages.
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/internal/fieldpath"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FieldViolations converts the validation errors contained in err into BadRequest field violations.
+// Errors reported for protobuf messages already carry their proto field path (e.g. "addresses[0].zip_code").
+// For other errors, msg (the validated value) is used to translate Go field names into proto field paths.
+func FieldViolations(err error, msg any) []*errdetails.BadRequest_FieldViolation {
+	typ := reflect.TypeOf(msg)
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, ve := range veritas.ValidationErrors(err) {
+		field := fieldpath.Translate(typ, ve.Path, protoName)
+		if ve.Path == "" {
+			field = fieldpath.Find(typ, ve.TypeName, ve.FieldName, protoName)
+		}
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: ve.Rule,
+		})
+	}
+	return violations
+}
+
+// BadRequest converts the validation errors contained in err into a BadRequest.
+// It returns nil if err contains no validation errors.
+func BadRequest(err error, msg any) *errdetails.BadRequest {
+	violations := FieldViolations(err, msg)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &errdetails.BadRequest{FieldViolations: violations}
+}
+
+// Status converts an error returned by veritas.Validator.Validate into a gRPC status.
+// Validation failures become InvalidArgument with a BadRequest detail,
+// context errors keep their gRPC meaning, and any other error becomes Internal.
+func Status(err error, msg any) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	var fatal *veritas.FatalError
+	switch {
+	case errors.As(err, &fatal):
+		return status.New(codes.Internal, "validation could not be performed")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err)
+	}
+
+	br := BadRequest(err, msg)
+	if br == nil {
+		return status.New(codes.Internal, "validation could not be performed")
+	}
+	st := status.New(codes.InvalidArgument, "validation failed")
+	if withDetails, err := st.WithDetails(br); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// UnaryServerInterceptor returns an interceptor that validates each request message with v
+// before calling the handler. Invalid requests are rejected with the status built by Status.
+func UnaryServerInterceptor(v *veritas.Validator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := v.Validate(ctx, req); err != nil {
+			return nil, Status(err, req).Err()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that validates each message received
+// from the client with v. RecvMsg returns the status built by Status for invalid messages.
+func StreamServerInterceptor(v *veritas.Validator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatingStream{ServerStream: ss, validator: v})
+	}
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+	validator *veritas.Validator
+}
+
+func (s *validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if err := s.validator.Validate(s.Context(), m); err != nil {
+		return Status(err, m).Err()
+	}
+	return nil
+}
+
+// protoName returns the proto field name of a field of a generated message struct,
+// taken from its `protobuf` tag ("bytes,1,opt,name=zip_code,json=zipCode,proto3").
+// Fields without the tag fall back to the snake_case form of the Go name.
+func protoName(f reflect.StructField) string {
+	if tag, ok := f.Tag.Lookup("protobuf"); ok {
+		for _, part := range strings.Split(tag, ",") {
+			if name, ok := strings.CutPrefix(part, "name="); ok {
+				return name
+			}
+		}
+	}
+	if oneof, ok := f.Tag.Lookup("protobuf_oneof"); ok {
+		return oneof
+	}
+	return snakeCase(f.Name)
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
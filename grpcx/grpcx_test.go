@@ -0,0 +1,205 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/veritas"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testRules = `{
+	"google.protobuf.StringValue": {
+		"fieldRules": {
+			"value": ["self.size() >= 3"]
+		}
+	}
+}`
+
+// echoServiceDesc describes a hand-written service, so that the test does not need generated code.
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "veritas.test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Echo",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(wrapperspb.StringValue)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return req, nil
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/veritas.test.Echo/Echo"}, handler)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Collect",
+			ClientStreams: true,
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				n := 0
+				for {
+					in := new(wrapperspb.StringValue)
+					if err := stream.RecvMsg(in); err != nil {
+						if errors.Is(err, io.EOF) {
+							return stream.SendMsg(wrapperspb.Int64(int64(n)))
+						}
+						return err
+					}
+					n++
+				}
+			},
+		},
+	},
+}
+
+func newTestClient(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	v, err := veritas.NewValidator(
+		veritas.WithRuleProvider(veritas.NewBytesRuleProvider([]byte(testRules))),
+		veritas.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(v)),
+		grpc.StreamInterceptor(StreamServerInterceptor(v)),
+	)
+	server.RegisterService(&echoServiceDesc, struct{}{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func wantInvalidArgument(t *testing.T, err error, want *errdetails.BadRequest) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("error is not a gRPC status: %v", err)
+	}
+	if st.Code() != codes.InvalidArgument {
+		t.Errorf("unexpected code: got %v, want %v", st.Code(), codes.InvalidArgument)
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("unexpected number of details: %d", len(details))
+	}
+	got, ok := details[0].(*errdetails.BadRequest)
+	if !ok {
+		t.Fatalf("unexpected detail type: %T", details[0])
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("BadRequest mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	conn := newTestClient(t)
+	ctx := context.Background()
+
+	t.Run("valid", func(t *testing.T) {
+		out := new(wrapperspb.StringValue)
+		if err := conn.Invoke(ctx, "/veritas.test.Echo/Echo", wrapperspb.String("hello"), out); err != nil {
+			t.Fatalf("Invoke() unexpected error: %v", err)
+		}
+		if out.GetValue() != "hello" {
+			t.Errorf("unexpected response: %q", out.GetValue())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		err := conn.Invoke(ctx, "/veritas.test.Echo/Echo", wrapperspb.String("hi"), new(wrapperspb.StringValue))
+		wantInvalidArgument(t, err, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "value", Description: "self.size() >= 3"},
+			},
+		})
+	})
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	conn := newTestClient(t)
+	ctx := context.Background()
+
+	stream, err := conn.NewStream(ctx, &echoServiceDesc.Streams[0], "/veritas.test.Echo/Collect")
+	if err != nil {
+		t.Fatalf("NewStream() unexpected error: %v", err)
+	}
+	for _, s := range []string{"hello", "hi"} {
+		if err := stream.SendMsg(wrapperspb.String(s)); err != nil {
+			break // the server may already have rejected the stream
+		}
+	}
+	stream.CloseSend()
+
+	err = stream.RecvMsg(new(wrapperspb.Int64Value))
+	wantInvalidArgument(t, err, &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "value", Description: "self.size() >= 3"},
+		},
+	})
+}
+
+type testAddress struct {
+	ZipCode string `protobuf:"bytes,1,opt,name=zip_code,json=zipCode,proto3" json:"zip_code,omitempty"`
+}
+
+type testUser struct {
+	DisplayName string         `protobuf:"bytes,1,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Addresses   []*testAddress `protobuf:"bytes,2,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	HomeAddress *testAddress
+}
+
+func TestFieldViolations(t *testing.T) {
+	err := errors.Join(
+		veritas.NewValidationError("grpcx.testUser", "DisplayName", `self != ""`),
+		veritas.NewValidationError("grpcx.testUser", "HomeAddress", `self != null`),
+		&veritas.ValidationError{TypeName: "grpcx.testAddress", FieldName: "ZipCode", Rule: `self.size() == 7`, Path: "Addresses[1].ZipCode"},
+		// Without a path, a field of a type that is used more than once cannot be located.
+		veritas.NewValidationError("grpcx.testAddress", "ZipCode", `self.size() == 7`),
+		veritas.NewValidationError("grpcx.testUser", "", `self.Addresses.size() > 0`),
+	)
+	got := FieldViolations(err, &testUser{})
+	want := []*errdetails.BadRequest_FieldViolation{
+		{Field: "display_name", Description: `self != ""`},
+		{Field: "home_address", Description: `self != null`},
+		{Field: "addresses[1].zip_code", Description: `self.size() == 7`},
+		{Field: "ZipCode", Description: `self.size() == 7`},
+		{Field: "", Description: `self.Addresses.size() > 0`},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("FieldViolations() mismatch (-want +got):\n%s", diff)
+	}
+}
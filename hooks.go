@@ -0,0 +1,134 @@
+package veritas
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/podhmo/veritas/internal/fieldpath"
+)
+
+// Validatable is implemented by types that validate themselves in Go.
+// It is useful for invariants that are far easier to write in Go than in CEL.
+//
+// The Validator calls ValidateHook on every value it reaches (the top-level object and nested
+// structs, pointers, slice elements and map values). A returned error is merged into the same
+// error tree as the CEL rules: ValidationErrors are kept as they are, and any other error
+// is wrapped in a ValidationError for the type.
+//
+// The method is not named Validate, so that types whose own Validate method calls
+// Validator.Validate on themselves are not called back, which would recurse forever.
+// For the same reason, ValidateHook must not call Validator.Validate on its receiver.
+type Validatable interface {
+	ValidateHook(ctx context.Context) error
+}
+
+// ReportingValidatable is a variant of Validatable that receives the path of the value
+// being validated (e.g. "Profiles[0]", empty for the top-level object) and reports
+// failures through a Reporter, so that they can be attributed to individual fields.
+type ReportingValidatable interface {
+	ValidateWithReporter(ctx context.Context, path string, r Reporter)
+}
+
+// Reporter collects validation failures reported by a ReportingValidatable.
+type Reporter interface {
+	// Report records a failure. fieldName is the Go field name, or empty for the whole value.
+	Report(fieldName, message string)
+}
+
+// HookMode controls which kinds of validation a Validator runs.
+type HookMode int
+
+const (
+	// HookModeBoth runs both CEL rules and Validatable hooks. This is the default.
+	HookModeBoth HookMode = iota
+	// HookModeCELOnly runs only CEL rules; Validatable hooks are ignored.
+	HookModeCELOnly
+	// HookModeHooksOnly runs only Validatable hooks; CEL rules are ignored.
+	HookModeHooksOnly
+)
+
+// WithHookMode sets whether CEL rules, Validatable hooks, or both are run.
+func WithHookMode(mode HookMode) ValidatorOption {
+	return func(o *validatorOptions) {
+		o.hookMode = mode
+	}
+}
+
+func (v *Validator) runsCEL() bool {
+	return v.hookMode != HookModeHooksOnly
+}
+
+func (v *Validator) runsHooks() bool {
+	return v.hookMode != HookModeCELOnly
+}
+
+// reporter is the Reporter passed to ReportingValidatable hooks.
+type reporter struct {
+	typeName  string
+	path      string
+	allErrors *[]error
+}
+
+func (r *reporter) Report(fieldName, message string) {
+	path := r.path
+	if fieldName != "" {
+		path = fieldpath.Join(path, fieldName)
+	}
+	*r.allErrors = append(*r.allErrors, newValidationErrorAt(path, r.typeName, fieldName, message))
+}
+
+// runHooks calls the Validatable and ReportingValidatable hooks implemented by obj.
+// Hooks with pointer receivers are also called for values, on a copy.
+func (v *Validator) runHooks(ctx context.Context, obj any, typeName, path string, allErrors *[]error) {
+	if !v.runsHooks() {
+		return
+	}
+
+	// Prefer the pointer, so that both value and pointer receivers are found.
+	target := obj
+	if rv := reflect.ValueOf(obj); rv.Kind() != reflect.Ptr {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		target = ptr.Interface()
+	}
+
+	if h, ok := target.(ReportingValidatable); ok {
+		h.ValidateWithReporter(ctx, path, &reporter{typeName: typeName, path: path, allErrors: allErrors})
+	}
+
+	h, ok := target.(Validatable)
+	if !ok {
+		return
+	}
+	err := h.ValidateHook(ctx)
+	if err == nil {
+		return
+	}
+
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		if ve, ok := err.(*ValidationError); ok {
+			if ve.TypeName == "" || ve.Path == "" {
+				copied := *ve
+				if copied.TypeName == "" {
+					copied.TypeName = typeName
+				}
+				if copied.Path == "" {
+					copied.Path = fieldpath.Join(path, ve.FieldName)
+				}
+				ve = &copied
+			}
+			*allErrors = append(*allErrors, ve)
+			continue
+		}
+		*allErrors = append(*allErrors, &ValidationError{
+			TypeName: typeName,
+			Rule:     err.Error(),
+			Path:     path,
+			Err:      err,
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package veritas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var errHookNegative = errors.New("total must not be negative")
+
+type hookLine struct {
+	Qty   int
+	Price int
+}
+
+// ValidateWithReporter has a pointer receiver, so it is called on a copy for values.
+func (l *hookLine) ValidateWithReporter(ctx context.Context, path string, r Reporter) {
+	if l.Qty <= 0 {
+		r.Report("Qty", "quantity must be positive")
+	}
+}
+
+type hookOrder struct {
+	Name  string
+	Total int
+	Lines []hookLine
+	Main  *hookLine
+}
+
+func (o hookOrder) ValidateHook(ctx context.Context) error {
+	var errs []error
+	if o.Total < 0 {
+		errs = append(errs, errHookNegative)
+	}
+	if o.Name == "forbidden" {
+		errs = append(errs, NewValidationError("", "Name", "name is forbidden"))
+	}
+	return errors.Join(errs...)
+}
+
+func TestValidator_Validate_Hooks(t *testing.T) {
+	rules := map[string]ValidationRuleSet{
+		"github.com/podhmo/veritas.hookOrder": {
+			FieldRules: map[string][]string{"Name": {`self != ""`}},
+		},
+	}
+	adapter := func(obj any) (map[string]any, error) {
+		o := obj.(hookOrder)
+		return map[string]any{"Name": o.Name}, nil
+	}
+
+	newValidator := func(t *testing.T, mode HookMode) *Validator {
+		t.Helper()
+		v, err := NewValidator(
+			WithRuleProvider(&mapRuleProvider{rules: rules}),
+			WithTypeAdapters(map[reflect.Type]TypeAdapterTarget{
+				reflect.TypeOf(hookOrder{}): {TargetName: "github.com/podhmo/veritas.hookOrder", Adapter: adapter},
+			}),
+			WithHookMode(mode),
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		)
+		if err != nil {
+			t.Fatalf("NewValidator() failed: %v", err)
+		}
+		return v
+	}
+
+	invalid := &hookOrder{
+		Total: -1,
+		Lines: []hookLine{{Qty: 1}, {Qty: 0}},
+		Main:  &hookLine{Qty: 0},
+	}
+
+	tests := []struct {
+		name string
+		mode HookMode
+		obj  any
+		want []string // "path|type.field: rule"
+	}{
+		{
+			name: "valid",
+			mode: HookModeBoth,
+			obj:  &hookOrder{Name: "ok", Lines: []hookLine{{Qty: 1}}},
+		},
+		{
+			name: "both",
+			mode: HookModeBoth,
+			obj:  invalid,
+			want: []string{
+				"Lines[1].Qty: quantity must be positive",
+				"Main.Qty: quantity must be positive",
+				"Name: self != \"\"",
+				"github.com/podhmo/veritas.hookOrder: total must not be negative",
+			},
+		},
+		{
+			name: "cel only",
+			mode: HookModeCELOnly,
+			obj:  invalid,
+			want: []string{"Name: self != \"\""},
+		},
+		{
+			name: "hooks only",
+			mode: HookModeHooksOnly,
+			obj:  invalid,
+			want: []string{
+				"Lines[1].Qty: quantity must be positive",
+				"Main.Qty: quantity must be positive",
+				"github.com/podhmo/veritas.hookOrder: total must not be negative",
+			},
+		},
+		{
+			name: "validation error from hook",
+			mode: HookModeHooksOnly,
+			obj:  hookOrder{Name: "forbidden"},
+			want: []string{"Name: name is forbidden"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newValidator(t, tt.mode)
+			err := v.Validate(context.Background(), tt.obj)
+
+			var got []string
+			for _, ve := range ValidationErrors(err) {
+				where := ve.Path
+				if where == "" {
+					where = ve.TypeName
+					if ve.FieldName != "" {
+						where += "." + ve.FieldName
+					}
+				}
+				got = append(got, where+": "+ve.Rule)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Validate() mismatch (-want +got):\n%s\nerr: %v", diff, err)
+			}
+		})
+	}
+
+	t.Run("hook errors are unwrappable", func(t *testing.T) {
+		v := newValidator(t, HookModeBoth)
+		err := v.Validate(context.Background(), hookOrder{Name: "ok", Total: -1})
+		if !errors.Is(err, errHookNegative) {
+			t.Errorf("errors.Is(err, errHookNegative) = false, err: %v", err)
+		}
+	})
+}
+
+// selfValidating validates itself with a Validator, as types commonly do in their Validate method.
+type selfValidating struct {
+	Name string
+	v    *Validator
+}
+
+func (s selfValidating) Validate(ctx context.Context) error {
+	return s.v.Validate(ctx, s)
+}
+
+func TestValidator_Validate_SelfValidatingType(t *testing.T) {
+	rules := map[string]ValidationRuleSet{
+		"github.com/podhmo/veritas.selfValidating": {
+			FieldRules: map[string][]string{"Name": {`self != ""`}},
+		},
+	}
+	v, err := NewValidator(
+		WithRuleProvider(&mapRuleProvider{rules: rules}),
+		WithTypes(selfValidating{}),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+
+	// Validate is not a hook, so it does not recurse.
+	err = selfValidating{v: v}.Validate(context.Background())
+	if got := ToErrorMap(err); !reflect.DeepEqual(got, map[string]string{"Name": `self != ""`}) {
+		t.Errorf("Validate() errors = %v, err: %v", got, err)
+	}
+}
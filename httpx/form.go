@@ -0,0 +1,289 @@
+package httpx
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/internal/fieldpath"
+)
+
+// DefaultMaxMemory is the maximum number of bytes of a multipart form held in memory by BindForm.
+// The rest is stored on disk in temporary files, as with http.Request.ParseMultipartForm.
+const DefaultMaxMemory = 32 << 20
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// BindQuery decodes the query string of the request into a T using `query` tags,
+// and validates it with the validator stored in the request context.
+//
+// Conversion and validation failures are returned as *Problem, with field names
+// taken from the `query` tags of T.
+func BindQuery[T any](r *http.Request) (T, error) {
+	var dst T
+	v, ok := ValidatorFromContext(r.Context())
+	if !ok {
+		return dst, ErrNoValidator
+	}
+	return BindValues[T](r.Context(), v, r.URL.Query(), "query")
+}
+
+// BindForm decodes the form body of the request (urlencoded or multipart) into a T
+// using `form` tags, and validates it with the validator stored in the request context.
+// Fields of type *multipart.FileHeader or []*multipart.FileHeader receive uploaded files.
+//
+// Conversion and validation failures are returned as *Problem, with field names
+// taken from the `form` tags of T.
+func BindForm[T any](r *http.Request) (T, error) {
+	var dst T
+	v, ok := ValidatorFromContext(r.Context())
+	if !ok {
+		return dst, ErrNoValidator
+	}
+
+	var files map[string][]*multipart.FileHeader
+	if err := r.ParseMultipartForm(DefaultMaxMemory); err != nil {
+		if !errors.Is(err, http.ErrNotMultipart) {
+			p := NewProblem(http.StatusBadRequest, "request body could not be decoded")
+			p.InvalidParams = []InvalidParam{{Reason: err.Error()}}
+			return dst, p
+		}
+	} else if r.MultipartForm != nil {
+		files = r.MultipartForm.File
+	}
+	return bind[T](r.Context(), v, r.PostForm, files, "form")
+}
+
+// BindValues decodes values into a T using the given struct tag (e.g. "form" or "query"),
+// and validates the result with v.
+//
+// Supported field types are strings, booleans, integers, floats, time.Time (RFC 3339),
+// encoding.TextUnmarshaler implementations, pointers to these, and slices of these.
+// Nested structs are decoded from dotted names (e.g. "address.zip").
+//
+// Conversion and validation failures are returned together as *Problem, with field names
+// taken from the struct tag. Validation failures of fields that could not be converted are omitted.
+func BindValues[T any](ctx context.Context, v *veritas.Validator, values url.Values, tag string) (T, error) {
+	return bind[T](ctx, v, values, nil, tag)
+}
+
+func bind[T any](ctx context.Context, v *veritas.Validator, values url.Values, files map[string][]*multipart.FileHeader, tag string) (T, error) {
+	var dst T
+	typ := reflect.TypeOf(dst)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return dst, fmt.Errorf("httpx: cannot bind into %v, a struct type is required", typ)
+	}
+
+	d := &valuesDecoder{values: values, files: files, tag: tag}
+	d.decodeStruct(reflect.ValueOf(&dst).Elem(), "")
+
+	var p *Problem
+	if err := v.Validate(ctx, dst); err != nil {
+		p = toProblem(err, typ, tag)
+		if p.Status >= http.StatusInternalServerError {
+			return dst, p
+		}
+	}
+
+	if len(d.params) == 0 {
+		if p != nil {
+			return dst, p
+		}
+		return dst, nil
+	}
+
+	failed := make(map[string]bool, len(d.params))
+	for _, param := range d.params {
+		failed[param.Name] = true
+	}
+	merged := NewProblem(http.StatusBadRequest, "request validation failed")
+	merged.InvalidParams = d.params
+	if p != nil {
+		for _, param := range p.InvalidParams {
+			if !failed[param.Name] {
+				merged.InvalidParams = append(merged.InvalidParams, param)
+			}
+		}
+	}
+	return dst, merged
+}
+
+// valuesDecoder decodes url.Values into a struct, collecting a conversion error per field.
+type valuesDecoder struct {
+	values url.Values
+	files  map[string][]*multipart.FileHeader
+	tag    string
+	params []InvalidParam
+}
+
+func (d *valuesDecoder) decodeStruct(rv reflect.Value, prefix string) {
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := fieldpath.Tag(d.tag)(f)
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+
+		if f.Anonymous && f.Tag.Get(d.tag) == "" && f.Type.Kind() == reflect.Struct {
+			d.decodeStruct(fv, prefix) // promoted fields share the parent's namespace
+			continue
+		}
+		d.decodeField(fv, fieldpath.Join(prefix, name))
+	}
+}
+
+func (d *valuesDecoder) decodeField(fv reflect.Value, name string) {
+	switch {
+	case fv.Type() == fileHeaderType:
+		if headers := d.files[name]; len(headers) > 0 {
+			fv.Set(reflect.ValueOf(headers[0]))
+		}
+		return
+	case fv.Kind() == reflect.Slice && fv.Type().Elem() == fileHeaderType:
+		if headers := d.files[name]; len(headers) > 0 {
+			fv.Set(reflect.ValueOf(headers))
+		}
+		return
+	case isScalar(fv.Type()):
+		// handled below
+	case fv.Kind() == reflect.Struct:
+		d.decodeStruct(fv, name)
+		return
+	case fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct:
+		if !d.hasPrefix(name + ".") {
+			return
+		}
+		fv.Set(reflect.New(fv.Type().Elem()))
+		d.decodeStruct(fv.Elem(), name)
+		return
+	}
+
+	raw, ok := d.values[name]
+	if !ok || len(raw) == 0 {
+		return
+	}
+
+	if fv.Kind() == reflect.Slice && !reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(fv.Type(), len(raw), len(raw))
+		for i, s := range raw {
+			if err := setScalar(slice.Index(i), s); err != nil {
+				d.params = append(d.params, InvalidParam{Name: name, Reason: err.Error()})
+				return
+			}
+		}
+		fv.Set(slice)
+		return
+	}
+
+	if err := setScalar(fv, raw[0]); err != nil {
+		d.params = append(d.params, InvalidParam{Name: name, Reason: err.Error()})
+	}
+}
+
+func (d *valuesDecoder) hasPrefix(prefix string) bool {
+	for k := range d.values {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	for k := range d.files {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isScalar reports whether values of typ are decoded from a single string (or a list of them, for slices).
+func isScalar(typ reflect.Type) bool {
+	if typ == timeType || typ.Implements(textUnmarshalerType) || reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return isScalar(typ.Elem())
+	case reflect.Struct, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan, reflect.Array:
+		return false
+	default:
+		return true
+	}
+}
+
+// setScalar converts s into the type of fv and stores it.
+func setScalar(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setScalar(elem.Elem(), s); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+
+	if fv.Type() == timeType {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected RFC 3339", s)
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if fv.CanAddr() {
+		if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := u.UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("invalid value %q: %v", s, err)
+			}
+			return nil
+		}
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/veritas"
+)
+
+type testSearch struct {
+	Query string     `query:"q"`
+	Page  int        `query:"page"`
+	Tags  []string   `query:"tag"`
+	Since *time.Time `query:"since"`
+	Debug bool       `query:"-"`
+}
+
+type testSignup struct {
+	Name    string                `form:"name"`
+	Age     uint8                 `form:"age"`
+	Address testAddress           `form:"address"`
+	Avatar  *multipart.FileHeader `form:"avatar"`
+}
+
+const testFormRules = `{
+	"github.com/podhmo/veritas/httpx.testSearch": {
+		"fieldRules": {
+			"Query": ["self.size() >= 2"],
+			"Page": ["self >= 1"]
+		}
+	},
+	"github.com/podhmo/veritas/httpx.testSignup": {
+		"fieldRules": {
+			"Name": ["self != \"\""],
+			"Age": ["self >= 18"]
+		}
+	},
+	"github.com/podhmo/veritas/httpx.testAddress": {
+		"fieldRules": {
+			"Zip": ["self.size() == 7"]
+		}
+	}
+}`
+
+func newFormValidator(t *testing.T) *veritas.Validator {
+	t.Helper()
+	v, err := veritas.NewValidator(
+		veritas.WithRuleProvider(veritas.NewBytesRuleProvider([]byte(testFormRules))),
+		veritas.WithTypes(testSearch{}, testSignup{}, testAddress{}),
+		veritas.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	return v
+}
+
+func TestBindValues(t *testing.T) {
+	v := newFormValidator(t)
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		values     url.Values
+		want       testSearch
+		wantParams []InvalidParam
+	}{
+		{
+			name:   "success",
+			values: url.Values{"q": {"go"}, "page": {"2"}, "tag": {"a", "b"}, "since": {"2024-01-02T03:04:05Z"}, "-": {"true"}},
+			want:   testSearch{Query: "go", Page: 2, Tags: []string{"a", "b"}, Since: &since},
+		},
+		{
+			name:   "validation error",
+			values: url.Values{"q": {"g"}, "page": {"1"}},
+			want:   testSearch{Query: "g", Page: 1},
+			wantParams: []InvalidParam{
+				{Name: "q", Reason: "self.size() >= 2"},
+			},
+		},
+		{
+			name:   "conversion error",
+			values: url.Values{"q": {"g"}, "page": {"two"}, "since": {"yesterday"}},
+			want:   testSearch{Query: "g"},
+			wantParams: []InvalidParam{
+				{Name: "page", Reason: `invalid integer "two"`},
+				{Name: "q", Reason: "self.size() >= 2"},
+				{Name: "since", Reason: `invalid time "yesterday", expected RFC 3339`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BindValues[testSearch](context.Background(), v, tt.values, "query")
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("BindValues() mismatch (-want +got):\n%s", diff)
+			}
+			if tt.wantParams == nil {
+				if err != nil {
+					t.Fatalf("BindValues() unexpected error: %v", err)
+				}
+				return
+			}
+
+			p, ok := err.(*Problem)
+			if !ok {
+				t.Fatalf("BindValues() error = %v, want *Problem", err)
+			}
+			if p.Status != http.StatusBadRequest {
+				t.Errorf("unexpected status: got %d, want %d", p.Status, http.StatusBadRequest)
+			}
+			sort.Slice(p.InvalidParams, func(i, j int) bool {
+				return p.InvalidParams[i].Name < p.InvalidParams[j].Name
+			})
+			if diff := cmp.Diff(tt.wantParams, p.InvalidParams); diff != "" {
+				t.Errorf("invalid params mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBindQuery(t *testing.T) {
+	v := newFormValidator(t)
+	handler := Middleware(v)(func(w http.ResponseWriter, r *http.Request) error {
+		search, err := BindQuery[testSearch](r)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(search)
+	})
+
+	req := httptest.NewRequest("GET", "/search?q=g&page=x", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status code: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentTypeProblemJSON {
+		t.Errorf("unexpected content type: got %q, want %q", got, ContentTypeProblemJSON)
+	}
+	var p Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+	sort.Slice(p.InvalidParams, func(i, j int) bool {
+		return p.InvalidParams[i].Name < p.InvalidParams[j].Name
+	})
+	want := []InvalidParam{
+		{Name: "page", Reason: `invalid integer "x"`},
+		{Name: "q", Reason: "self.size() >= 2"},
+	}
+	if diff := cmp.Diff(want, p.InvalidParams); diff != "" {
+		t.Errorf("invalid params mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	v := newFormValidator(t)
+
+	t.Run("urlencoded", func(t *testing.T) {
+		body := url.Values{"name": {""}, "age": {"300"}, "address.zip": {"123"}}.Encode()
+		req := httptest.NewRequest("POST", "/signup", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = req.WithContext(WithValidator(req.Context(), v))
+
+		_, err := BindForm[testSignup](req)
+		p, ok := err.(*Problem)
+		if !ok {
+			t.Fatalf("BindForm() error = %v, want *Problem", err)
+		}
+		sort.Slice(p.InvalidParams, func(i, j int) bool {
+			return p.InvalidParams[i].Name < p.InvalidParams[j].Name
+		})
+		want := []InvalidParam{
+			{Name: "address.zip", Reason: "self.size() == 7"},
+			{Name: "age", Reason: `invalid unsigned integer "300"`},
+			{Name: "name", Reason: `self != ""`},
+		}
+		if diff := cmp.Diff(want, p.InvalidParams); diff != "" {
+			t.Errorf("invalid params mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("name", "foo")
+		mw.WriteField("age", "20")
+		mw.WriteField("address.zip", "1234567")
+		fw, err := mw.CreateFormFile("avatar", "avatar.png")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		fw.Write([]byte("png"))
+		mw.Close()
+
+		req := httptest.NewRequest("POST", "/signup", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req = req.WithContext(WithValidator(req.Context(), v))
+
+		got, err := BindForm[testSignup](req)
+		if err != nil {
+			t.Fatalf("BindForm() unexpected error: %v", err)
+		}
+		if got.Name != "foo" || got.Age != 20 || got.Address.Zip != "1234567" {
+			t.Errorf("unexpected result: %+v", got)
+		}
+		if got.Avatar == nil || got.Avatar.Filename != "avatar.png" {
+			t.Errorf("unexpected avatar: %+v", got.Avatar)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+
+	"github.com/podhmo/veritas"
+)
+
+// ErrNoValidator is returned by DecodeAndValidate when no validator is stored in the request context.
+var ErrNoValidator = errors.New("httpx: no validator in request context")
+
+type validatorKey struct{}
+
+// WithValidator returns a copy of ctx that carries the validator.
+func WithValidator(ctx context.Context, v *veritas.Validator) context.Context {
+	return context.WithValue(ctx, validatorKey{}, v)
+}
+
+// ValidatorFromContext returns the validator stored in ctx, if any.
+func ValidatorFromContext(ctx context.Context) (*veritas.Validator, bool) {
+	v, ok := ctx.Value(validatorKey{}).(*veritas.Validator)
+	return v, ok && v != nil
+}
+
+// DecodeAndValidate decodes the JSON request body into a T and validates it
+// with the validator stored in the request context (see Middleware and WithValidator).
+//
+// Decoding and validation failures are returned as *Problem, with field names
+// expressed as JSON paths derived from the `json` tags of T.
+func DecodeAndValidate[T any](r *http.Request) (T, error) {
+	var body T
+
+	v, ok := ValidatorFromContext(r.Context())
+	if !ok {
+		return body, ErrNoValidator
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return body, decodeProblem(err)
+	}
+
+	if err := v.Validate(r.Context(), body); err != nil {
+		return body, ToProblem(err, reflect.TypeOf(body))
+	}
+	return body, nil
+}
+
+// HandlerFunc is an HTTP handler that may fail.
+// Errors returned by it are rendered as application/problem+json by Middleware.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Middleware returns a function that adapts a HandlerFunc into an http.Handler.
+// The resulting handler stores v in the request context, so that DecodeAndValidate can use it,
+// and renders any error returned by the wrapped handler as an RFC 7807 problem.
+func Middleware(v *veritas.Validator) func(HandlerFunc) http.Handler {
+	return func(h HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(WithValidator(r.Context(), v))
+			err := h(w, r)
+			if err == nil {
+				return
+			}
+
+			p := *ToProblem(err, nil) // copy, so that shared problems are not mutated
+			if p.Status >= http.StatusInternalServerError {
+				slog.ErrorContext(r.Context(), "request failed", "err", err)
+			}
+			if p.Instance == "" {
+				p.Instance = r.URL.Path
+			}
+			if err := WriteProblem(w, &p); err != nil {
+				slog.ErrorContext(r.Context(), "failed to encode problem response", "err", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/veritas"
+)
+
+type testAddress struct {
+	Zip string `json:"zip" form:"zip"`
+}
+
+type testUser struct {
+	Name    string        `json:"name"`
+	Age     int           `json:"age"`
+	Address *testAddress  `json:"address,omitempty"`
+	Others  []testAddress `json:"others,omitempty"`
+}
+
+const testRules = `{
+	"github.com/podhmo/veritas/httpx.testUser": {
+		"typeRules": ["self.Age < 200"],
+		"fieldRules": {
+			"Name": ["self != \"\""],
+			"Age": ["self >= 0"]
+		}
+	},
+	"github.com/podhmo/veritas/httpx.testAddress": {
+		"fieldRules": {
+			"Zip": ["self.size() == 7"]
+		}
+	}
+}`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	v, err := veritas.NewValidator(
+		veritas.WithRuleProvider(veritas.NewBytesRuleProvider([]byte(testRules))),
+		veritas.WithTypes(testUser{}, testAddress{}),
+		veritas.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /users", Middleware(v)(func(w http.ResponseWriter, r *http.Request) error {
+		user, err := DecodeAndValidate[testUser](r)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		return json.NewEncoder(w).Encode(user)
+	}))
+	mux.Handle("GET /fail", Middleware(v)(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("database is down")
+	}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMiddleware(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		want       *Problem
+	}{
+		{
+			name:       "success",
+			method:     "POST",
+			path:       "/users",
+			body:       `{"name": "foo", "age": 20}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "validation error",
+			method:     "POST",
+			path:       "/users",
+			body:       `{"name": "", "age": 200}`,
+			wantStatus: http.StatusBadRequest,
+			want: &Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request validation failed",
+				Instance: "/users",
+				InvalidParams: []InvalidParam{
+					{Name: "", Reason: "self.Age < 200"},
+					{Name: "name", Reason: `self != ""`},
+				},
+			},
+		},
+		{
+			name:       "nested validation error",
+			method:     "POST",
+			path:       "/users",
+			body:       `{"name": "foo", "address": {"zip": "123"}}`,
+			wantStatus: http.StatusBadRequest,
+			want: &Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request validation failed",
+				Instance: "/users",
+				InvalidParams: []InvalidParam{
+					{Name: "address.zip", Reason: "self.size() == 7"},
+				},
+			},
+		},
+		{
+			name:       "validation error in a list",
+			method:     "POST",
+			path:       "/users",
+			body:       `{"name": "foo", "others": [{"zip": "1234567"}, {"zip": "123"}]}`,
+			wantStatus: http.StatusBadRequest,
+			want: &Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request validation failed",
+				Instance: "/users",
+				InvalidParams: []InvalidParam{
+					{Name: "others[1].zip", Reason: "self.size() == 7"},
+				},
+			},
+		},
+		{
+			name:       "syntax error",
+			method:     "POST",
+			path:       "/users",
+			body:       `{"name": "foo",}`,
+			wantStatus: http.StatusBadRequest,
+			want: &Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body could not be decoded",
+				Instance: "/users",
+				InvalidParams: []InvalidParam{
+					{Reason: "malformed JSON at offset 16: invalid character '}' looking for beginning of object key string"},
+				},
+			},
+		},
+		{
+			name:       "type error",
+			method:     "POST",
+			path:       "/users",
+			body:       `{"name": "foo", "address": {"zip": 1234567}}`,
+			wantStatus: http.StatusBadRequest,
+			want: &Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body could not be decoded",
+				Instance: "/users",
+				InvalidParams: []InvalidParam{
+					{Name: "address.zip", Reason: "expected string but got JSON number"},
+				},
+			},
+		},
+		{
+			name:       "empty body",
+			method:     "POST",
+			path:       "/users",
+			body:       ``,
+			wantStatus: http.StatusBadRequest,
+			want: &Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "request body could not be decoded",
+				Instance: "/users",
+				InvalidParams: []InvalidParam{
+					{Reason: "request body must not be empty"},
+				},
+			},
+		},
+		{
+			name:       "internal error",
+			method:     "GET",
+			path:       "/fail",
+			wantStatus: http.StatusInternalServerError,
+			want: &Problem{
+				Type:     "about:blank",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Instance: "/fail",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("unexpected status code: got %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			if got := resp.Header.Get("Content-Type"); got != ContentTypeProblemJSON {
+				t.Errorf("unexpected content type: got %q, want %q", got, ContentTypeProblemJSON)
+			}
+			var got Problem
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode problem: %v", err)
+			}
+			// Validation errors are reported in map order.
+			sort.Slice(got.InvalidParams, func(i, j int) bool {
+				return got.InvalidParams[i].Name < got.InvalidParams[j].Name
+			})
+			if diff := cmp.Diff(tt.want, &got); diff != "" {
+				t.Errorf("problem mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeAndValidate_NoValidator(t *testing.T) {
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name": "foo"}`))
+	if _, err := DecodeAndValidate[testUser](req); !errors.Is(err, ErrNoValidator) {
+		t.Errorf("DecodeAndValidate() error = %v, want %v", err, ErrNoValidator)
+	}
+}
@@ -0,0 +1,126 @@
+// Package httpx provides net/http helpers for decoding and validating request bodies
+// with veritas, and for reporting failures as RFC 7807 problem details.
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/internal/fieldpath"
+)
+
+// ContentTypeProblemJSON is the media type of an RFC 7807 problem details document.
+const ContentTypeProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object.
+// It implements the error interface so that it can be returned from handlers.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+// InvalidParam describes a single invalid request parameter.
+// Name is the JSON path of the offending field; it is empty for errors that apply to the whole body.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return fmt.Sprintf("%s: %s", p.Title, p.Detail)
+}
+
+// NewProblem creates a problem with the given status code, using the status text as its title.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WriteProblem writes the problem as an application/problem+json response.
+func WriteProblem(w http.ResponseWriter, p *Problem) error {
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// ToProblem converts an error into a problem.
+// Problems are returned as-is, veritas validation errors become a 400 problem with
+// one invalid param per failed rule, and any other error becomes an opaque 500 problem.
+// typ is the type that was decoded; it is used to translate Go field names into JSON names.
+func ToProblem(err error, typ reflect.Type) *Problem {
+	return toProblem(err, typ, "json")
+}
+
+// toProblem is ToProblem with field names taken from the given struct tag.
+func toProblem(err error, typ reflect.Type, tag string) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var fatal *veritas.FatalError
+	if errors.As(err, &fatal) {
+		return NewProblem(http.StatusInternalServerError, "")
+	}
+
+	validationErrs := veritas.ValidationErrors(err)
+	if len(validationErrs) == 0 {
+		return NewProblem(http.StatusInternalServerError, "")
+	}
+
+	p = NewProblem(http.StatusBadRequest, "request validation failed")
+	nameOf := fieldpath.Tag(tag)
+	for _, ve := range validationErrs {
+		name := fieldpath.Translate(typ, ve.Path, nameOf)
+		if ve.Path == "" {
+			name = fieldpath.Find(typ, ve.TypeName, ve.FieldName, nameOf)
+		}
+		p.InvalidParams = append(p.InvalidParams, InvalidParam{
+			Name:   name,
+			Reason: ve.Rule,
+		})
+	}
+	return p
+}
+
+// decodeProblem converts an error returned by json.Decoder into a problem.
+func decodeProblem(err error) *Problem {
+	p := NewProblem(http.StatusBadRequest, "request body could not be decoded")
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		p.InvalidParams = []InvalidParam{{
+			Reason: fmt.Sprintf("malformed JSON at offset %d: %s", syntaxErr.Offset, syntaxErr.Error()),
+		}}
+	case errors.As(err, &typeErr):
+		p.InvalidParams = []InvalidParam{{
+			Name:   typeErr.Field,
+			Reason: fmt.Sprintf("expected %s but got JSON %s", typeErr.Type, typeErr.Value),
+		}}
+	case errors.Is(err, io.EOF):
+		p.InvalidParams = []InvalidParam{{Reason: "request body must not be empty"}}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		p.InvalidParams = []InvalidParam{{Reason: "malformed JSON: unexpected end of input"}}
+	default:
+		p.InvalidParams = []InvalidParam{{Reason: err.Error()}}
+	}
+	return p
+}
@@ -0,0 +1,203 @@
+// Package fieldpath translates the locations reported by veritas validation errors
+// into paths of external field names (JSON names, form names, proto names, and so on).
+package fieldpath
+
+import (
+	"reflect"
+	"strings"
+)
+
+// NameFunc returns the external name of a struct field.
+// Returning "-" excludes the field (and everything below it) from the search.
+type NameFunc func(f reflect.StructField) string
+
+// Tag returns a NameFunc that uses the first element of the given struct tag,
+// falling back to the Go field name, as encoding/json does.
+func Tag(tag string) NameFunc {
+	return func(f reflect.StructField) string {
+		value := f.Tag.Get(tag)
+		if value == "" {
+			return f.Name
+		}
+		name, _, _ := strings.Cut(value, ",")
+		if name == "" {
+			return f.Name
+		}
+		return name
+	}
+}
+
+// Translate translates path, a location of Go field names as set in veritas.ValidationError.Path
+// (e.g. "Billing.Lines[0].UnitPrice"), into external names (e.g. "billing.lines[0].unit_price"),
+// walking the fields of typ. Indices and map keys are kept as they are, and promoted fields are
+// inlined as encoding/json does.
+//
+// The rest of the path is kept unchanged from the first element that is not a Go field of a struct:
+// the fields of protobuf messages, which are already reported with their proto names, and the
+// fields of values that are validated through a TypeAdapter.
+func Translate(typ reflect.Type, path string, nameOf NameFunc) string {
+	var out string
+	rest := path
+	for rest != "" && typ != nil {
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				break
+			}
+			out += rest[:end+1]
+			typ = elem(typ)
+			rest = strings.TrimPrefix(rest[end+1:], ".")
+			continue
+		}
+
+		name := rest
+		if i := strings.IndexAny(rest, ".["); i != -1 {
+			name = rest[:i]
+		}
+		typ = deref(typ)
+		if typ.Kind() != reflect.Struct || isProtoMessage(typ) {
+			break
+		}
+		f, ok := typ.FieldByName(name)
+		if !ok || !f.IsExported() {
+			break
+		}
+		for t, i := typ, 0; i < len(f.Index); i++ {
+			step := deref(t).Field(f.Index[i])
+			if stepName := nameOf(step); stepName == "-" {
+				out = Join(out, step.Name)
+			} else if !step.Anonymous || stepName != step.Name {
+				out = Join(out, stepName) // embedded structs are inlined, as encoding/json does
+			}
+			t = step.Type
+		}
+		typ = f.Type
+		rest = strings.TrimPrefix(rest[len(name):], ".")
+	}
+	if rest != "" && !strings.HasPrefix(rest, "[") {
+		return Join(out, rest)
+	}
+	return out + rest
+}
+
+// Find returns the path of the field named fieldName in the struct named typeName,
+// searching the structs that are reachable from typ. Path elements are joined with ".".
+// An empty fieldName refers to the struct itself.
+//
+// It is the fallback for errors that carry no path, such as the ones built by hand with
+// veritas.NewValidationError. The field is only translated if it is found exactly once, and not
+// in an element of a slice, an array or a map, whose index is unknown; otherwise fieldName is
+// returned unchanged.
+func Find(typ reflect.Type, typeName, fieldName string, nameOf NameFunc) string {
+	if typ == nil {
+		return fieldName
+	}
+	var found []match
+	find(typ, typeName, fieldName, nameOf, "", false, make(map[reflect.Type]bool), &found)
+	if len(found) != 1 || found[0].inElem {
+		return fieldName
+	}
+	return found[0].path
+}
+
+type match struct {
+	path   string
+	inElem bool // The path is not usable, as it lacks the index of an element.
+}
+
+// find appends to found each match of the field below typ, whose path is prefix.
+func find(typ reflect.Type, typeName, fieldName string, nameOf NameFunc, prefix string, inElem bool, visiting map[reflect.Type]bool, found *[]match) {
+	typ = deref(typ)
+	if e := elem(typ); e != nil {
+		find(e, typeName, fieldName, nameOf, "", true, visiting, found)
+		return
+	}
+	if typ.Kind() != reflect.Struct || visiting[typ] {
+		return
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	if MatchesTypeName(typ, typeName) {
+		if fieldName == "" {
+			*found = append(*found, match{path: prefix, inElem: inElem})
+			return
+		}
+		if f, ok := typ.FieldByName(fieldName); ok {
+			*found = append(*found, match{path: Join(prefix, nameOf(f)), inElem: inElem})
+			return
+		}
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := nameOf(f)
+		if name == "-" {
+			continue
+		}
+		childPrefix := Join(prefix, name)
+		if f.Anonymous && name == f.Name {
+			childPrefix = prefix // promoted fields are inlined, as encoding/json does
+		}
+		find(f.Type, typeName, fieldName, nameOf, childPrefix, inElem, visiting, found)
+	}
+}
+
+func deref(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// elem returns the type of the elements of a slice, an array or a map of typ, or nil.
+func elem(typ reflect.Type) reflect.Type {
+	switch typ = deref(typ); typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return typ.Elem()
+	}
+	return nil
+}
+
+// isProtoMessage reports whether typ is the struct of a generated protobuf message.
+func isProtoMessage(typ reflect.Type) bool {
+	_, ok := reflect.PointerTo(typ).MethodByName("ProtoReflect")
+	return ok
+}
+
+// MatchesTypeName reports whether typ is the type named by a rule key.
+// Rule keys are either fully qualified ("github.com/foo/bar.User") or qualified
+// by the package name only ("bar.User"); generic keys ("bar.Box[T]") match any instantiation.
+func MatchesTypeName(typ reflect.Type, typeName string) bool {
+	name := typ.Name()
+	if i := strings.Index(name, "["); i != -1 {
+		name = name[:i]
+	}
+	if i := strings.Index(typeName, "["); i != -1 {
+		typeName = typeName[:i]
+	}
+
+	pkgPath := typ.PkgPath()
+	if pkgPath == "" {
+		return typeName == name
+	}
+	if typeName == pkgPath+"."+name {
+		return true
+	}
+	pkgName := pkgPath[strings.LastIndex(pkgPath, "/")+1:]
+	return typeName == pkgName+"."+name
+}
+
+// Join appends name to the path prefix. An empty name refers to prefix itself.
+func Join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	if name == "" {
+		return prefix
+	}
+	return prefix + "." + name
+}
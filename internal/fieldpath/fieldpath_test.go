@@ -0,0 +1,152 @@
+package fieldpath
+
+import (
+	"reflect"
+	"testing"
+)
+
+type address struct {
+	Zip string `json:"zip"`
+}
+
+type Audit struct {
+	CreatedBy string `json:"created_by"`
+}
+
+type order struct {
+	Audit
+	ID       string             `json:"id"`
+	Billing  *address           `json:"billing"`
+	Lines    []line             `json:"lines"`
+	Labels   map[string]address `json:"labels"`
+	Internal string             `json:"-"`
+	Note     string
+}
+
+type line struct {
+	Qty  int      `json:"qty"`
+	Ship *address `json:"ship,omitempty"`
+}
+
+type node struct {
+	Name     string  `json:"name"`
+	Children []*node `json:"children"`
+}
+
+type protoMessage struct {
+	SingleString string `protobuf:"bytes,1,opt,name=single_string,proto3"`
+}
+
+func (*protoMessage) ProtoReflect() {}
+
+type holder struct {
+	Payment *protoMessage `json:"payment"`
+}
+
+func TestTag(t *testing.T) {
+	typ := reflect.TypeOf(order{})
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "ID", want: "id"},
+		{field: "Internal", want: "-"},
+		{field: "Note", want: "Note"},
+		{field: "Audit", want: "Audit"},
+	}
+	for _, tt := range tests {
+		f, _ := typ.FieldByName(tt.field)
+		if got := Tag("json")(f); got != tt.want {
+			t.Errorf("Tag(json)(%s) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  reflect.Type
+		path string
+		want string
+	}{
+		{name: "empty", typ: reflect.TypeOf(order{}), path: "", want: ""},
+		{name: "field", typ: reflect.TypeOf(order{}), path: "ID", want: "id"},
+		{name: "pointer", typ: reflect.TypeOf(&order{}), path: "Billing.Zip", want: "billing.zip"},
+		{name: "slice", typ: reflect.TypeOf(order{}), path: "Lines[1].Ship.Zip", want: "lines[1].ship.zip"},
+		{name: "map", typ: reflect.TypeOf(order{}), path: "Labels[home].Zip", want: "labels[home].zip"},
+		{name: "promoted", typ: reflect.TypeOf(order{}), path: "CreatedBy", want: "created_by"},
+		{name: "embedded", typ: reflect.TypeOf(order{}), path: "Audit.CreatedBy", want: "created_by"},
+		{name: "excluded", typ: reflect.TypeOf(order{}), path: "Internal", want: "Internal"},
+		{name: "untagged", typ: reflect.TypeOf(order{}), path: "Note", want: "Note"},
+		{name: "recursive", typ: reflect.TypeOf(node{}), path: "Children[0].Children[2].Name", want: "children[0].children[2].name"},
+		{name: "unknown", typ: reflect.TypeOf(order{}), path: "Lines[0].Unknown.Name", want: "lines[0].Unknown.Name"},
+		{name: "proto", typ: reflect.TypeOf(holder{}), path: "Payment.single_string", want: "payment.single_string"},
+		{name: "nil type", typ: nil, path: "Lines[0].Qty", want: "Lines[0].Qty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Translate(tt.typ, tt.path, Tag("json")); got != tt.want {
+				t.Errorf("Translate(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name      string
+		typ       reflect.Type
+		typeName  string
+		fieldName string
+		want      string
+	}{
+		{name: "root field", typ: reflect.TypeOf(order{}), typeName: "fieldpath.order", fieldName: "ID", want: "id"},
+		{name: "root type", typ: reflect.TypeOf(order{}), typeName: "fieldpath.order", fieldName: "", want: ""},
+		{name: "promoted", typ: reflect.TypeOf(order{}), typeName: "fieldpath.order", fieldName: "CreatedBy", want: "created_by"},
+		{name: "nested once", typ: reflect.TypeOf(&order{}), typeName: "github.com/podhmo/veritas/internal/fieldpath.Audit", fieldName: "CreatedBy", want: "created_by"},
+		// address is reachable through Billing, Lines[*].Ship and Labels[*]: the guess is not made.
+		{name: "ambiguous", typ: reflect.TypeOf(order{}), typeName: "fieldpath.address", fieldName: "Zip", want: "Zip"},
+		// line is only reachable through a slice, whose index is unknown.
+		{name: "in a slice", typ: reflect.TypeOf(order{}), typeName: "fieldpath.line", fieldName: "Qty", want: "Qty"},
+		{name: "recursive", typ: reflect.TypeOf(node{}), typeName: "fieldpath.node", fieldName: "Name", want: "name"},
+		{name: "not found", typ: reflect.TypeOf(order{}), typeName: "fieldpath.missing", fieldName: "Name", want: "Name"},
+		{name: "nil type", typ: nil, typeName: "fieldpath.order", fieldName: "ID", want: "ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Find(tt.typ, tt.typeName, tt.fieldName, Tag("json")); got != tt.want {
+				t.Errorf("Find(%q, %q) = %q, want %q", tt.typeName, tt.fieldName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesTypeName(t *testing.T) {
+	typ := reflect.TypeOf(order{})
+	tests := []struct {
+		typeName string
+		want     bool
+	}{
+		{typeName: "github.com/podhmo/veritas/internal/fieldpath.order", want: true},
+		{typeName: "fieldpath.order", want: true},
+		{typeName: "other.order", want: false},
+		{typeName: "fieldpath.line", want: false},
+	}
+	for _, tt := range tests {
+		if got := MatchesTypeName(typ, tt.typeName); got != tt.want {
+			t.Errorf("MatchesTypeName(%q) = %v, want %v", tt.typeName, got, tt.want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if got := Join("", "name"); got != "name" {
+		t.Errorf("Join(\"\", name) = %q", got)
+	}
+	if got := Join("lines[0]", "qty"); got != "lines[0].qty" {
+		t.Errorf("Join(lines[0], qty) = %q", got)
+	}
+	if got := Join("lines[0]", ""); got != "lines[0]" {
+		t.Errorf("Join(lines[0], \"\") = %q", got)
+	}
+}
@@ -2,22 +2,37 @@ package lint
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/types"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 
-	"github.com/google/cel-go/cel"
 	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/cmd/veritas/parser"
+	"github.com/podhmo/veritas/lint/required"
+	"github.com/podhmo/veritas/lint/satisfiable"
+	"github.com/podhmo/veritas/validatetag"
 	"golang.org/x/tools/go/analysis"
 )
 
 var Analyzer = &analysis.Analyzer{
 	Name: "veritas",
-	Doc:  "veritas is a linter for veritas rules",
+	Doc:  "veritas is a linter for veritas rules in validate tags, @cel comments and rules.json",
 	Run:  run,
 }
 
+// Analyzers are the analyzers that `veritas -lint` runs. Each problem is reported by one of them.
+var Analyzers = []*analysis.Analyzer{
+	Analyzer,
+	required.Analyzer,
+	satisfiable.Analyzer,
+}
+
 func loadRules(pass *analysis.Pass) (map[string]veritas.ValidationRuleSet, error) {
 	if len(pass.Files) == 0 {
 		return make(map[string]veritas.ValidationRuleSet), nil
@@ -57,6 +72,10 @@ func loadRules(pass *analysis.Pass) (map[string]veritas.ValidationRuleSet, error
 }
 
 func run(pass *analysis.Pass) (interface{}, error) {
+	// Strict, like the generator with -strict, so that rules that would be dropped are reported.
+	p := parser.NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)), parser.WithStrict())
+	checkSource(pass, p)
+
 	rules, err := loadRules(pass)
 	if err != nil {
 		return nil, err
@@ -64,65 +83,126 @@ func run(pass *analysis.Pass) (interface{}, error) {
 	if len(rules) == 0 {
 		return nil, nil
 	}
+	checkRulesFile(pass, p, rules)
+	return nil, nil
+}
 
-	st, err := cel.NewEnv(veritas.DefaultFunctions()...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create cel env: %w", err)
-	}
-	env, err := st.Extend(cel.Variable("self", cel.DynType))
-	if err != nil {
-		return nil, fmt.Errorf("failed to extend cel env: %w", err)
+// checkSource reports the problems with the rules written in validate tags and `// @cel:` comments,
+// at the tag or comment, as the generator does: tags that cannot be translated, and rules that
+// do not type-check against the struct's fields. Problems with a rule of a tag are reported at
+// the rule, with a suggested fix where there is an obvious one. A 'required' that does not apply
+// is left to the required analyzer.
+func checkSource(pass *analysis.Pass, p *parser.Parser) {
+	_, _, err := p.ParseDirectly(parser.PackageInfo{
+		PkgPath:   pass.Pkg.Path(),
+		Syntax:    pass.Files,
+		TypesInfo: pass.TypesInfo,
+		Types:     pass.Pkg,
+	})
+	tags := collectTags(pass)
+	for _, e := range parser.Errors(err) {
+		pos := e.Pos
+		if !pos.IsValid() && len(pass.Files) > 0 {
+			pos = pass.Files[0].Package
+		}
+		diag := analysis.Diagnostic{Pos: pos, Message: e.Err.Error()}
+		var tagErr *validatetag.Error
+		if tag, ok := tags[e.Pos]; ok && errors.As(e.Err, &tagErr) && misusedRequired(tag, tagErr.Offset) {
+			continue
+		}
+		if tag, ok := tags[e.Pos]; ok && tag.exact && errors.As(e.Err, &tagErr) {
+			hint, fixes := tag.suggest(tagErr.Offset)
+			diag.Pos = tag.pos(tagErr.Offset)
+			diag.Message += hint
+			diag.SuggestedFixes = fixes
+		}
+		pass.Report(diag)
 	}
+}
 
+// misusedRequired reports whether the problem at offset in the validate value of tag is a
+// 'required' that does not apply to its value, which the required analyzer reports, with a fix.
+func misusedRequired(tag *tagInfo, offset int) bool {
+	r, _ := ruleAt(tag.rules, tag.typ, offset)
+	return r != nil && r.Kind == validatetag.Shorthand && r.Name == "required" && len(r.Args) == 0
+}
+
+// checkRulesFile reports the problems with the rules of rules.json for the package's structs.
+// Rule sets are keyed by the fully qualified type name, e.g. "example.com/m/models.User".
+func checkRulesFile(pass *analysis.Pass, p *parser.Parser, rules map[string]veritas.ValidationRuleSet) {
 	for _, file := range pass.Files {
 		ast.Inspect(file, func(n ast.Node) bool {
 			ts, ok := n.(*ast.TypeSpec)
 			if !ok {
 				return true
 			}
-			structType, ok := ts.Type.(*ast.StructType)
-			if !ok {
+			if _, ok := ts.Type.(*ast.StructType); !ok {
+				return true
+			}
+			obj := pass.TypesInfo.Defs[ts.Name]
+			if obj == nil {
 				return true
 			}
 
-			typeName := ts.Name.Name
+			typeName := pass.Pkg.Path() + "." + ts.Name.Name
 			ruleSet, ok := rules[typeName]
 			if !ok {
 				return true
 			}
+			typ := obj.Type()
 
 			// Check TypeRules
 			for _, rule := range ruleSet.TypeRules {
-				if _, issues := env.Compile(rule); issues != nil && issues.Err() != nil {
-					pass.Reportf(ts.Pos(), "invalid type rule for %s: %s", typeName, issues.Err())
+				if err := p.CheckRule(typ, rule); err != nil {
+					pass.Reportf(ts.Pos(), "invalid type rule for %s: %s", typeName, err)
 				}
 			}
 
-			// Check FieldRules (CEL syntax)
-			for fieldName, fieldRules := range ruleSet.FieldRules {
-				for _, rule := range fieldRules {
-					if _, issues := env.Compile(rule); issues != nil && issues.Err() != nil {
-						pass.Reportf(ts.Pos(), "invalid field rule for %s.%s: %s", typeName, fieldName, issues.Err())
+			// Check FieldRules, against the field's type
+			for _, fieldName := range sortedKeys(ruleSet.FieldRules) {
+				field := lookupField(pass, typ, fieldName)
+				if field == nil {
+					pass.Reportf(ts.Pos(), "field %s in rules for %s does not exist in struct", fieldName, typeName)
+					continue
+				}
+				for _, rule := range ruleSet.FieldRules[fieldName] {
+					if err := p.CheckRule(field.Type(), rule); err != nil {
+						pass.Reportf(ts.Pos(), "invalid field rule for %s.%s: %s", typeName, fieldName, err)
 					}
 				}
 			}
 
-			// Check FieldRules (field existence)
-			definedFields := make(map[string]bool)
-			for _, field := range structType.Fields.List {
-				for _, name := range field.Names {
-					definedFields[name.Name] = true
+			// Check CrossFieldRules, against the struct
+			for _, fieldName := range sortedKeys(ruleSet.CrossFieldRules) {
+				if lookupField(pass, typ, fieldName) == nil {
+					pass.Reportf(ts.Pos(), "field %s in cross-field rules for %s does not exist in struct", fieldName, typeName)
+					continue
 				}
-			}
-
-			for fieldName := range ruleSet.FieldRules {
-				if !definedFields[fieldName] {
-					pass.Reportf(ts.Pos(), "field %s in rules for %s does not exist in struct", fieldName, typeName)
+				for _, rule := range ruleSet.CrossFieldRules[fieldName] {
+					if err := p.CheckRule(typ, rule); err != nil {
+						pass.Reportf(ts.Pos(), "invalid cross-field rule for %s.%s: %s", typeName, fieldName, err)
+					}
 				}
 			}
 			return true
 		})
 	}
+}
 
-	return nil, nil
+// lookupField returns the exported field (possibly promoted from an embedded struct) named name of typ.
+func lookupField(pass *analysis.Pass, typ types.Type, name string) *types.Var {
+	obj, _, _ := types.LookupFieldOrMethod(typ, false, pass.Pkg, name)
+	if v, ok := obj.(*types.Var); ok && v.IsField() && v.Exported() {
+		return v
+	}
+	return nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
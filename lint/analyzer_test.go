@@ -1,13 +1,48 @@
 package lint_test
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/podhmo/veritas/lint"
 	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
 )
 
 func TestAnalyzer(t *testing.T) {
 	testdata := analysistest.TestData()
-	analysistest.Run(t, testdata, lint.Analyzer, "a", "b", "c")
+	analysistest.RunWithSuggestedFixes(t, testdata, lint.Analyzer, "a", "b", "c", "d", "e")
+}
+
+func TestAnalyzers(t *testing.T) {
+	// A problem is reported once by the analyzers of `veritas -lint`, not by each of them.
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.LoadAllSyntax,
+		Dir:  testdata,
+		Env:  append(os.Environ(), "GOPATH="+testdata, "GO111MODULE=off", "GOPROXY=off"),
+	}, "f")
+	if err != nil {
+		t.Fatalf("packages.Load() failed: %v", err)
+	}
+	graph, err := checker.Analyze(lint.Analyzers, pkgs, nil)
+	if err != nil {
+		t.Fatalf("checker.Analyze() failed: %v", err)
+	}
+	var got []string
+	for _, act := range graph.Roots {
+		for _, d := range act.Diagnostics {
+			got = append(got, act.Analyzer.Name+": "+d.Message)
+		}
+	}
+	want := []string{"required: 'required' tag can only be used with pointer types"}
+	if !slices.Equal(got, want) {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
 }
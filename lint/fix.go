@@ -0,0 +1,257 @@
+package lint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/podhmo/veritas/cmd/veritas/parser"
+	"github.com/podhmo/veritas/validatetag"
+	"golang.org/x/tools/go/analysis"
+)
+
+// tagInfo is the struct tag of a field, with what is needed to fix its validate value.
+type tagInfo struct {
+	lit    *ast.BasicLit
+	typ    types.Type // The field's type.
+	owner  types.Type // The struct that declares the field.
+	offset int        // The offset of the validate value in lit.Value.
+	exact  bool       // Whether offsets in the validate value can be mapped to the source.
+	value  string
+	rules  []*validatetag.Rule
+}
+
+// collectTags returns the struct tags of the package that have a validate value, by position.
+func collectTags(pass *analysis.Pass) map[token.Pos]*tagInfo {
+	tags := make(map[token.Pos]*tagInfo)
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			st, ok := n.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				if field.Tag == nil {
+					continue
+				}
+				value, ok := reflect.StructTag(strings.Trim(field.Tag.Value, "`")).Lookup("validate")
+				if !ok {
+					continue
+				}
+				offset, exact := validatetag.ValueOffset(field.Tag.Value)
+				parsed, err := validatetag.Parse(value)
+				if err != nil {
+					parsed = &validatetag.Tag{}
+				}
+				tags[field.Tag.Pos()] = &tagInfo{
+					lit:    field.Tag,
+					typ:    pass.TypesInfo.TypeOf(field.Type),
+					owner:  pass.TypesInfo.TypeOf(st),
+					offset: offset,
+					exact:  exact,
+					value:  value,
+					rules:  parsed.Rules,
+				}
+			}
+			return true
+		})
+	}
+	return tags
+}
+
+// pos returns the position of offset in the validate value.
+func (t *tagInfo) pos(offset int) token.Pos {
+	return t.lit.Pos() + token.Pos(t.offset+offset)
+}
+
+// suggest returns the fixes for a problem with the rule at offset in the validate value,
+// and a hint to add to the message of the problem.
+func (t *tagInfo) suggest(offset int) (string, []analysis.SuggestedFix) {
+	r, typ := ruleAt(t.rules, t.typ, offset)
+	if r == nil {
+		return "", nil
+	}
+	shorthands := parser.Shorthands()
+	switch {
+	case r.Kind == validatetag.Shorthand && !slices.Contains(shorthands, r.Name):
+		if name := closest(r.Name, shorthands); name != "" {
+			return fmt.Sprintf(" (did you mean %s?)", name), t.replace(r.Pos, r.Pos+len(r.Name), name, fmt.Sprintf("Replace %s with %s", r.Name, name))
+		}
+
+	case r.Kind == validatetag.Shorthand && parser.IsFieldReference(r.Name) && len(r.Args) > 0 && !hasField(t.owner, r.Args[0]):
+		start, end := t.extent(r)
+		return "", t.replace(start, end, "", fmt.Sprintf("Remove %s, as field %s does not exist", r.Name, r.Args[0]))
+
+	case r.Kind == validatetag.Directive && r.Name == "dive" && isMap(typ):
+		// go-playground/validator's syntax for maps: "dive,keys,...,endkeys,..." or "dive,...".
+		if rewritten := mapRules(r); rewritten != "" {
+			return " (use keys and values for maps)", t.replace(r.Pos, ruleEnd(r), rewritten, fmt.Sprintf("Rewrite as %q", rewritten))
+		}
+	}
+	return "", nil
+}
+
+// replace returns a fix replacing value[start:end] with text.
+func (t *tagInfo) replace(start, end int, text, message string) []analysis.SuggestedFix {
+	return []analysis.SuggestedFix{{
+		Message:   message,
+		TextEdits: []analysis.TextEdit{{Pos: t.pos(start), End: t.pos(end), NewText: []byte(text)}},
+	}}
+}
+
+// extent returns the range of r in the validate value together with a comma separating it
+// from its neighbors, so that removing it leaves a valid list.
+func (t *tagInfo) extent(r *validatetag.Rule) (int, int) {
+	if i := strings.Index(t.value[r.End:], ","); i != -1 {
+		end := r.End + i + 1
+		for end < len(t.value) && t.value[end] == ' ' {
+			end++
+		}
+		return r.Pos, end
+	}
+	if i := strings.LastIndex(t.value[:r.Pos], ","); i != -1 {
+		return i, r.End
+	}
+	return r.Pos, r.End
+}
+
+// ruleAt returns the rule at offset in rules, which apply to values of type t,
+// and the type the rule applies to.
+func ruleAt(rules []*validatetag.Rule, t types.Type, offset int) (*validatetag.Rule, types.Type) {
+	for _, r := range rules {
+		if r.Pos == offset {
+			return r, t
+		}
+		if r.Kind == validatetag.Directive {
+			if found, typ := ruleAt(r.Body, itemType(r.Name, t), offset); found != nil {
+				return found, typ
+			}
+		}
+	}
+	return nil, nil
+}
+
+// itemType returns the type of the items a directive applies to, or nil.
+func itemType(directive string, t types.Type) types.Type {
+	if t == nil {
+		return nil
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		if directive == "dive" {
+			return u.Elem()
+		}
+	case *types.Map:
+		switch directive {
+		case "keys":
+			return u.Key()
+		case "values":
+			return u.Elem()
+		}
+	}
+	return nil
+}
+
+// ruleEnd returns the end of r, including the body of a directive.
+func ruleEnd(r *validatetag.Rule) int {
+	if len(r.Body) == 0 {
+		return r.End
+	}
+	return ruleEnd(r.Body[len(r.Body)-1])
+}
+
+// mapRules rewrites dive, applied to a map, with keys and values:
+// "dive,keys,a,endkeys,b" becomes "keys,a,values,b", and "dive,b" becomes "values,b".
+func mapRules(dive *validatetag.Rule) string {
+	keys, values := []*validatetag.Rule(nil), dive.Body
+	if first := dive.Body[0]; first.Kind == validatetag.Directive && first.Name == "keys" {
+		keys, values = first.Body, nil
+		if i := slices.IndexFunc(keys, isEndKeys); i != -1 {
+			keys, values = keys[:i], keys[i+1:]
+		}
+		values = append(values, dive.Body[1:]...)
+	}
+	if slices.ContainsFunc(values, isEndKeys) {
+		return ""
+	}
+
+	var parts []string
+	if len(keys) > 0 {
+		parts = append(parts, "keys")
+		for _, r := range keys {
+			parts = append(parts, r.String())
+		}
+	}
+	if len(values) > 0 {
+		parts = append(parts, "values")
+		for _, r := range values {
+			parts = append(parts, r.String())
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func isEndKeys(r *validatetag.Rule) bool {
+	return r.Kind == validatetag.Shorthand && r.Name == "endkeys"
+}
+
+func isMap(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Map)
+	return ok
+}
+
+func hasField(owner types.Type, name string) bool {
+	if owner == nil {
+		return true // Unknown, so nothing is removed.
+	}
+	obj, _, _ := types.LookupFieldOrMethod(owner, false, nil, name)
+	v, ok := obj.(*types.Var)
+	return ok && v.IsField()
+}
+
+// closest returns the candidate nearest to name, if it is within two edits and
+// nearer than any other candidate.
+func closest(name string, candidates []string) string {
+	best, bestDist, tie := "", 3, false
+	for _, c := range candidates {
+		d := editDistance(name, c)
+		switch {
+		case d < bestDist:
+			best, bestDist, tie = c, d, false
+		case d == bestDist:
+			tie = true
+		}
+	}
+	if tie || bestDist >= len(name) {
+		return ""
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
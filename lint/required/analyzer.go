@@ -2,10 +2,13 @@ package required
 
 import (
 	"go/ast"
+	"go/token"
 	"go/types"
 	"reflect"
 	"strings"
 
+	"github.com/podhmo/veritas/cmd/veritas/parser"
+	"github.com/podhmo/veritas/validatetag"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 )
@@ -35,24 +38,88 @@ func run(pass *analysis.Pass) (interface{}, error) {
 					continue
 				}
 
-				hasRequired := false
-				rules := strings.Split(validateTag, ",")
-				for _, rule := range rules {
-					if rule == "required" {
-						hasRequired = true
-						break
-					}
+				parsed, err := validatetag.Parse(validateTag)
+				if err != nil {
+					pass.Reportf(field.Tag.Pos(), "invalid validate tag: %s", err)
+					continue
 				}
-
-				if hasRequired {
-					tv := pass.TypesInfo.TypeOf(field.Type)
-					if _, ok := tv.Underlying().(*types.Pointer); !ok {
-						pass.Reportf(field.Pos(), "'required' tag can only be used with pointer types")
-					}
+				tv := pass.TypesInfo.TypeOf(field.Type)
+				if tv == nil {
+					continue
 				}
+				misused := misusedRequired(parsed.Rules, tv)
+				if len(misused) == 0 {
+					continue
+				}
+				pass.Report(analysis.Diagnostic{
+					Pos:            field.Pos(),
+					Message:        "'required' tag can only be used with pointer types",
+					SuggestedFixes: nonzeroFix(field.Tag, misused),
+				})
 			}
 			return true
 		})
 	}
 	return nil, nil
 }
+
+// misuse is a 'required' rule on a value of a type other than a pointer.
+type misuse struct {
+	rule *validatetag.Rule
+	typ  types.Type
+}
+
+// misusedRequired returns the 'required' rules that do not apply to a pointer.
+// Rules inside dive, keys and values are checked against the element, key and value types.
+func misusedRequired(rules []*validatetag.Rule, tv types.Type) []misuse {
+	var misused []misuse
+	for _, r := range rules {
+		switch r.Kind {
+		case validatetag.Shorthand:
+			if r.Name != "required" {
+				continue
+			}
+			if _, ok := tv.Underlying().(*types.Pointer); !ok {
+				misused = append(misused, misuse{rule: r, typ: tv})
+			}
+		case validatetag.Directive:
+			var item types.Type
+			switch u := tv.Underlying().(type) {
+			case *types.Slice:
+				if r.Name == "dive" {
+					item = u.Elem()
+				}
+			case *types.Map:
+				if r.Name == "keys" {
+					item = u.Key()
+				} else if r.Name == "values" {
+					item = u.Elem()
+				}
+			}
+			if item != nil {
+				misused = append(misused, misusedRequired(r.Body, item)...)
+			}
+		}
+	}
+	return misused
+}
+
+// nonzeroFix replaces the misused 'required' rules of tag with 'nonzero', where it applies.
+func nonzeroFix(tag *ast.BasicLit, misused []misuse) []analysis.SuggestedFix {
+	offset, ok := validatetag.ValueOffset(tag.Value)
+	if !ok {
+		return nil
+	}
+	var edits []analysis.TextEdit
+	for _, m := range misused {
+		if !parser.ShorthandApplies("nonzero", m.typ) {
+			continue // e.g. a struct value, which is always present.
+		}
+		pos := tag.Pos() + token.Pos(offset+m.rule.Pos)
+		edits = append(edits, analysis.TextEdit{Pos: pos, End: pos + token.Pos(len(m.rule.Name)), NewText: []byte("nonzero")})
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+	return []analysis.SuggestedFix{{Message: "Replace required with nonzero", TextEdits: edits}}
+}
@@ -9,5 +9,5 @@ import (
 
 func Test(t *testing.T) {
 	testdata := analysistest.TestData()
-	analysistest.Run(t, testdata, required.Analyzer, "d")
+	analysistest.RunWithSuggestedFixes(t, testdata, required.Analyzer, "d")
 }
@@ -3,3 +3,11 @@ package d
 type Input struct {
 	Name string `validate:"required"` // want "'required' tag can only be used with pointer types"
 }
+
+type Items struct {
+	Pointers []*Input          `validate:"dive,required"`
+	Values   []Input           `validate:"dive,required"` // want "'required' tag can only be used with pointer types"
+	ByName   map[string]*Input `validate:"keys,nonzero,values,required"`
+	Counts   map[string]int    `validate:"values,required"` // want "'required' tag can only be used with pointer types"
+	Broken   *Input            `validate:"cel:f(1"`         // want "invalid validate tag: validate tag: column 6: unclosed '\\(' in CEL expression"
+}
@@ -0,0 +1,120 @@
+// Package rulefacts exports the rules of each named type as an analysis fact, so that the veritas
+// analyzers can reason about the rules of types declared in other packages: nested structs,
+// elements of slices and maps, embedded structs, and named types of fields.
+package rulefacts
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/cmd/veritas/parser"
+	"golang.org/x/tools/go/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:       "rulefacts",
+	Doc:        "export the validation rules of named types as facts, for the analysis of packages that use them",
+	Run:        run,
+	FactTypes:  []analysis.Fact{new(Rules)},
+	ResultType: reflect.TypeOf(new(Result)),
+}
+
+// Rules is the fact of the rules generated for a named type, as in its rule set,
+// with the types of the fields that have rules.
+type Rules struct {
+	veritas.ValidationRuleSet
+	FieldTypes map[string]string // By field name, qualified by package path.
+}
+
+func (*Rules) AFact() {}
+
+func (r *Rules) String() string {
+	parts := append([]string(nil), r.TypeRules...)
+	names := make([]string, 0, len(r.FieldTypes))
+	for name := range r.FieldTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rules := append(append([]string(nil), r.FieldRules[name]...), r.CrossFieldRules[name]...)
+		parts = append(parts, fmt.Sprintf("%s %s: %s", name, r.FieldTypes[name], strings.Join(rules, " && ")))
+	}
+	return "rules(" + strings.Join(parts, "; ") + ")"
+}
+
+// Result gives the rules of named types of the package being analyzed and of its dependencies.
+type Result struct {
+	pass *analysis.Pass
+}
+
+// Rules returns the rules of the named type obj, if it has any.
+func (r *Result) Rules(obj *types.TypeName) (*Rules, bool) {
+	var fact Rules
+	if !r.pass.ImportObjectFact(obj, &fact) {
+		return nil, false
+	}
+	return &fact, true
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	// Rules that do not type-check are reported by the veritas analyzer; the others are still facts.
+	p := parser.NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)), parser.WithUncompilableRules())
+	ruleSets, _, err := p.ParseDirectly(parser.PackageInfo{
+		PkgPath:   pass.Pkg.Path(),
+		Syntax:    pass.Files,
+		TypesInfo: pass.TypesInfo,
+		Types:     pass.Pkg,
+	})
+	if err != nil {
+		// Reported by the veritas analyzer; the package's types have no facts.
+		return &Result{pass: pass}, nil
+	}
+
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				obj, ok := pass.TypesInfo.Defs[typeSpec.Name].(*types.TypeName)
+				if !ok || typeSpec.TypeParams != nil {
+					continue // Generic types have rules by instantiation only.
+				}
+				ruleSet, ok := ruleSets[pass.Pkg.Path()+"."+obj.Name()]
+				if !ok {
+					continue
+				}
+				pass.ExportObjectFact(obj, &Rules{ValidationRuleSet: ruleSet, FieldTypes: fieldTypes(obj.Type(), ruleSet)})
+			}
+		}
+	}
+	return &Result{pass: pass}, nil
+}
+
+// fieldTypes returns the types of the fields of t that have rules in ruleSet.
+func fieldTypes(t types.Type, ruleSet veritas.ValidationRuleSet) map[string]string {
+	fields := make(map[string]string)
+	add := func(name string) {
+		obj, _, _ := types.LookupFieldOrMethod(t, false, nil, name)
+		if v, ok := obj.(*types.Var); ok && v.IsField() {
+			fields[name] = types.TypeString(v.Type(), (*types.Package).Path)
+		}
+	}
+	for name := range ruleSet.FieldRules {
+		add(name)
+	}
+	for name := range ruleSet.CrossFieldRules {
+		add(name)
+	}
+	return fields
+}
@@ -0,0 +1,13 @@
+package rulefacts_test
+
+import (
+	"testing"
+
+	"github.com/podhmo/veritas/lint/rulefacts"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, rulefacts.Analyzer, "addr", "user")
+}
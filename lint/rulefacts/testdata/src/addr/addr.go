@@ -0,0 +1,14 @@
+package addr
+
+// @cel: self.size() == 3
+type Code string // want Code:`rules\(self.size\(\) == 3\)`
+
+// @cel: self.Zip.size() == 5
+type Address struct { // want Address:`rules\(self.Zip.size\(\) == 5; City string: self != ""\)`
+	City string `validate:"nonzero"`
+	Zip  string
+}
+
+type Plain struct {
+	Name string
+}
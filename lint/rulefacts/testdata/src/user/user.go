@@ -0,0 +1,8 @@
+package user
+
+import "addr"
+
+type User struct { // want User:`rules\(Code addr.Code: self != ""; Home addr.Address: self.City != 'London'\)`
+	Home addr.Address `validate:"cel:self.City != 'London'"`
+	Code addr.Code    `validate:"nonzero"`
+}
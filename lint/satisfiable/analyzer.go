@@ -0,0 +1,380 @@
+// Package satisfiable reports validation rules that can never pass, that are implied by other
+// rules, or that always pass. It reasons about comparisons of values and of their sizes with
+// constants, "in" lists and patterns, in validate tags and `// @cel:` comments; the parts of
+// rules it does not understand are assumed to be satisfiable. The rules of the types of fields,
+// which are also applied by the Validator, are taken into account, from any package.
+//
+// Shorthands are understood through the rules that the generator makes of them. The tag syntax has
+// no min, max, len or oneof shorthands, so lengths, ranges and lists are only checked when they are
+// written as cel: rules, e.g. `cel:self.size() >= 10`; comparisons between fields and conditional
+// shorthands such as required_if are assumed to be satisfiable.
+package satisfiable
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/podhmo/veritas/cmd/veritas/parser"
+	"github.com/podhmo/veritas/lint/rulefacts"
+	"github.com/podhmo/veritas/validatetag"
+	"golang.org/x/tools/go/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "satisfiable",
+	Doc:      "check for validation rules that can never pass, are redundant, or always pass",
+	Run:      run,
+	Requires: []*analysis.Analyzer{rulefacts.Analyzer},
+}
+
+// rule is a rule of a validate tag or a `// @cel:` comment of a struct or of one of its fields,
+// or a rule of another type that applies to one of its fields. Only the former are reported.
+type rule struct {
+	text   string // As written, e.g. "nonzero" or "cel:self > 0".
+	field  string // The field the rule is written on, or "" for a rule of the struct.
+	origin string // For a rule of another type, the type or its field, e.g. "addr.Address.City".
+	pos    token.Pos
+	atoms  []atom // The conditions of the rule that are understood.
+	whole  bool   // Whether the rule is exactly the conjunction of atoms.
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	x, err := newExtractor()
+	if err != nil {
+		return nil, err
+	}
+	c := &checker{
+		pass:   pass,
+		parser: parser.NewParser(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		x:      x,
+		facts:  pass.ResultOf[rulefacts.Analyzer].(*rulefacts.Result),
+	}
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				obj := pass.TypesInfo.Defs[typeSpec.Name]
+				if !ok || obj == nil {
+					continue
+				}
+				// As in the parser, the group's doc comment does not belong to grouped specs.
+				doc := typeSpec.Doc
+				if doc == nil && !genDecl.Lparen.IsValid() {
+					doc = genDecl.Doc
+				}
+				c.checkStruct(typeSpec.Name.Name, obj.Type(), structType, doc)
+			}
+		}
+	}
+	return nil, nil
+}
+
+type checker struct {
+	pass   *analysis.Pass
+	parser *parser.Parser
+	x      *extractor
+	facts  *rulefacts.Result
+}
+
+// checkStruct checks the rules of a struct and of its fields together, since rules of the struct
+// may constrain fields too. The rules of an omitempty field are skipped for its zero value, so
+// they are checked apart, with its value assumed to be nonzero.
+func (c *checker) checkStruct(name string, typ types.Type, structType *ast.StructType, doc *ast.CommentGroup) {
+	context := c.fieldTypeRules(subject{"", typ}, maxDepth, make(map[types.Type]bool))
+	var rules []*rule
+	for _, cm := range parser.CELComments(doc) {
+		rules = append(rules, c.celRule(cm, "", subject{"", typ}))
+	}
+	for _, field := range structType.Fields.List {
+		tv := c.pass.TypesInfo.TypeOf(field.Type)
+		if tv == nil {
+			continue
+		}
+		for _, ident := range field.Names {
+			self := subject{"." + ident.Name, tv}
+			fieldRules, omitEmpty := c.tagRules(field.Tag, ident.Name, self)
+			for _, cm := range append(parser.CELComments(field.Doc), parser.CELComments(field.Comment)...) {
+				fieldRules = append(fieldRules, c.celRule(cm, ident.Name, self))
+			}
+			if omitEmpty == nil {
+				rules = append(rules, fieldRules...)
+				continue
+			}
+			c.check(name, fieldRules, omitEmpty, context)
+		}
+	}
+	c.check(name, rules, nil, context)
+}
+
+// maxDepth is how deep fieldTypeRules goes into nested structs.
+const maxDepth = 3
+
+// fieldTypeRules returns the rules of other types that the Validator applies to the fields of the
+// struct v: the rules of the named types of its fields, and of the structs nested in it, as
+// fields, embedded fields, or elements and values of slices and maps, down to depth.
+func (c *checker) fieldTypeRules(v subject, depth int, seen map[types.Type]bool) []*rule {
+	st, ok := deref(v.typ).Underlying().(*types.Struct)
+	if !ok || depth == 0 || seen[deref(v.typ)] {
+		return nil
+	}
+	seen[deref(v.typ)] = true
+	defer delete(seen, deref(v.typ))
+
+	var rules []*rule
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() {
+			continue
+		}
+		field := subject{v.path + "." + f.Name(), f.Type()}
+		rules = append(rules, c.typeRules(field)...)
+		rules = append(rules, c.fieldTypeRules(field, depth-1, seen)...)
+
+		// Only structs are validated as elements; the rules of other named types apply to fields.
+		var item subject
+		switch u := deref(f.Type()).Underlying().(type) {
+		case *types.Slice:
+			item = subject{field.path + "[]", u.Elem()}
+		case *types.Array:
+			item = subject{field.path + "[]", u.Elem()}
+		case *types.Map:
+			item = subject{field.path + "[value]", u.Elem()}
+		default:
+			continue
+		}
+		if _, ok := deref(item.typ).Underlying().(*types.Struct); ok {
+			rules = append(rules, c.typeRules(item)...)
+			rules = append(rules, c.fieldTypeRules(item, depth-1, seen)...)
+		}
+	}
+	return rules
+}
+
+// typeRules returns the rules of the named type of v, as exported by the rulefacts analyzer.
+func (c *checker) typeRules(v subject) []*rule {
+	named, ok := deref(v.typ).(*types.Named)
+	if !ok {
+		return nil
+	}
+	facts, ok := c.facts.Rules(named.Obj())
+	if !ok {
+		return nil
+	}
+	typeName := types.TypeString(named, types.RelativeTo(c.pass.Pkg))
+	var rules []*rule
+	add := func(text string, self subject, origin string) {
+		atoms, whole := c.x.atoms(text, self)
+		rules = append(rules, &rule{text: text, origin: origin, atoms: atoms, whole: whole})
+	}
+	for _, r := range facts.TypeRules {
+		add(r, v, typeName)
+	}
+	for _, name := range sortedKeys(facts.FieldRules) {
+		field, ok := fieldOf(v, name)
+		if !ok {
+			continue
+		}
+		for _, r := range facts.FieldRules[name] {
+			add(r, field, typeName+"."+name)
+		}
+	}
+	for _, name := range sortedKeys(facts.CrossFieldRules) {
+		for _, r := range facts.CrossFieldRules[name] {
+			add(r, v, typeName+"."+name)
+		}
+	}
+	return rules
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// celRule returns the rule of a `// @cel:` comment, about self.
+func (c *checker) celRule(cm parser.CELComment, field string, self subject) *rule {
+	atoms, whole := c.x.atoms(cm.Rule, self)
+	return &rule{text: cm.Rule, field: field, pos: cm.Pos, atoms: atoms, whole: whole}
+}
+
+// tagRules returns the rules of the validate tag of a field, about self. If the tag has omitempty,
+// premises are the atoms of the field's value being nonzero, and they are not nil.
+func (c *checker) tagRules(tag *ast.BasicLit, field string, self subject) (rules []*rule, premises []atom) {
+	if tag == nil {
+		return nil, nil
+	}
+	value, ok := reflect.StructTag(strings.Trim(tag.Value, "`")).Lookup("validate")
+	if !ok {
+		return nil, nil
+	}
+	parsed, err := validatetag.Parse(value)
+	if err != nil {
+		return nil, nil // Reported by the veritas analyzer.
+	}
+	offset, exact := validatetag.ValueOffset(tag.Value)
+
+	for _, r := range parsed.Rules {
+		if r.Kind == validatetag.Shorthand && r.Name == "omitempty" {
+			nonzero, err := c.parser.RuleCEL(&validatetag.Rule{Kind: validatetag.Shorthand, Name: "nonzero"}, self.typ)
+			if err == nil {
+				premises, _ = c.x.atoms(nonzero, self)
+			}
+			if premises == nil {
+				premises = []atom{}
+			}
+			continue
+		}
+		expr, err := c.parser.RuleCEL(r, self.typ)
+		if err != nil || expr == "" {
+			continue
+		}
+		pos := tag.Pos()
+		if exact {
+			pos += token.Pos(offset + r.Pos)
+		}
+		atoms, whole := c.x.atoms(expr, self)
+		rules = append(rules, &rule{text: r.String(), field: field, pos: pos, atoms: atoms, whole: whole})
+	}
+	return rules, premises
+}
+
+// check reports the rules, of the struct named name, that can never all pass, then the rules that
+// always pass, then the rules that are implied by others. premises are assumed to hold, and the
+// rules of context, from other types, are taken into account where they are about the same values.
+func (c *checker) check(name string, rules []*rule, premises []atom, context []*rule) {
+	pool := slices.DeleteFunc(slices.Clone(rules), func(r *rule) bool { return len(r.atoms) == 0 })
+	paths := make(map[string]bool)
+	for _, r := range pool {
+		for _, a := range r.atoms {
+			paths[a.path] = true
+		}
+	}
+	for _, r := range context {
+		if slices.ContainsFunc(r.atoms, func(a atom) bool { return paths[a.path] }) {
+			pool = append(pool, r)
+		}
+	}
+	local := func(r *rule) bool { return r.origin == "" }
+	holds := func(rules []*rule) []atom {
+		atoms := slices.Clone(premises)
+		for _, r := range rules {
+			atoms = append(atoms, r.atoms...)
+		}
+		return atoms
+	}
+
+	for !satisfiable(holds(pool)) {
+		core := minimal(pool, func(rules []*rule) bool { return !satisfiable(holds(rules)) })
+		pool = slices.DeleteFunc(pool, func(r *rule) bool { return slices.Contains(core, r) })
+		var at *rule
+		for _, r := range core {
+			if local(r) {
+				at = r
+			}
+		}
+		if at == nil {
+			continue // Rules of other types only, reported with those types.
+		}
+		verb := "pass"
+		switch {
+		case len(core) == 2:
+			verb = "both pass"
+		case len(core) > 2:
+			verb = "all pass"
+		}
+		c.report(name, at, "%s can never %s", list(core, at), verb)
+	}
+
+	pool = slices.DeleteFunc(pool, func(r *rule) bool {
+		if !local(r) || !r.whole || !implies(premises, r.atoms) {
+			return false
+		}
+		if len(premises) > 0 && !implies(nil, r.atoms) {
+			c.report(name, r, "%q always passes, as omitempty skips the zero value", r.text)
+		} else {
+			c.report(name, r, "%q always passes", r.text)
+		}
+		return true
+	})
+
+	// Later rules are kept, and earlier ones reported, when rules imply each other.
+	for i := len(pool) - 1; i >= 0; i-- {
+		r := pool[i]
+		others := slices.Delete(slices.Clone(pool), i, i+1)
+		if !local(r) || !r.whole || !implies(holds(others), r.atoms) {
+			continue
+		}
+		by := minimal(others, func(rules []*rule) bool { return implies(holds(rules), r.atoms) })
+		c.report(name, r, "%q is redundant, as it is implied by %s", r.text, list(by, r))
+		pool = others
+	}
+}
+
+// minimal returns a subset of rules for which holds is true, and from which no rule can be left
+// out. holds must be true for rules. The subset is in source order, with rules of other types last.
+func minimal(rules []*rule, holds func([]*rule) bool) []*rule {
+	subset := slices.Clone(rules)
+	for i := 0; i < len(subset); {
+		without := slices.Delete(slices.Clone(subset), i, i+1)
+		if holds(without) {
+			subset = without
+		} else {
+			i++
+		}
+	}
+	slices.SortStableFunc(subset, func(a, b *rule) int {
+		if a.origin != "" || b.origin != "" {
+			return strings.Compare(a.origin, b.origin)
+		}
+		return int(a.pos - b.pos)
+	})
+	return subset
+}
+
+// report reports a problem with r, of the struct named name, like the parser's errors.
+func (c *checker) report(name string, r *rule, format string, args ...any) {
+	prefix := name + ": "
+	if r.field != "" {
+		prefix += "field " + r.field + ": "
+	}
+	c.pass.Reportf(r.pos, "%s%s", prefix, fmt.Sprintf(format, args...))
+}
+
+// list returns the rules, quoted and joined with "and". Rules of other types, and of other fields
+// than the one of the rule reported at, are followed by where they are from.
+func list(rules []*rule, at *rule) string {
+	quoted := make([]string, len(rules))
+	for i, r := range rules {
+		quoted[i] = strconv.Quote(r.text)
+		switch {
+		case r.origin != "":
+			quoted[i] += " (on " + r.origin + ")"
+		case r.field == at.field:
+		case r.field == "":
+			quoted[i] += " (on the struct)"
+		default:
+			quoted[i] += " (on field " + r.field + ")"
+		}
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
+}
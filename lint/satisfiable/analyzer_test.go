@@ -0,0 +1,13 @@
+package satisfiable_test
+
+import (
+	"testing"
+
+	"github.com/podhmo/veritas/lint/satisfiable"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, satisfiable.Analyzer, "a", "b", "shorthands")
+}
@@ -0,0 +1,388 @@
+package satisfiable
+
+import (
+	"go/types"
+	"regexp"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/overloads"
+	celtypes "github.com/google/cel-go/common/types"
+)
+
+// atom is a condition on a value that rules are conjunctions of, such as "self.Age >= 18"
+// or "self.Name.size() > 0". Conditions that are not understood are left out.
+type atom struct {
+	path string     // The value: "" for 'self', ".Name" for a field, ".Tags[]" for its elements.
+	typ  types.Type // The Go type of the value at path.
+	size bool       // Whether the condition is on the size of the value rather than on the value.
+	op   string     // A comparison operator, "in", "matches", "null" or "notnull".
+	val  value      // The operand of a comparison.
+	set  []value    // The operand of "in".
+	re   *regexp.Regexp
+}
+
+type valueKind int
+
+const (
+	numberValue valueKind = iota + 1
+	stringValue
+	boolValue
+)
+
+// value is a constant that a value is compared with. Numbers of all types are float64.
+type value struct {
+	kind valueKind
+	num  float64
+	str  string
+	b    bool
+}
+
+func number(n float64) value { return value{kind: numberValue, num: n} }
+
+// compare returns -1, 0 or 1 as v is less than, equal to or greater than w, of the same kind.
+func (v value) compare(w value) int {
+	switch {
+	case v.kind == numberValue && v.num < w.num,
+		v.kind == stringValue && v.str < w.str,
+		v.kind == boolValue && !v.b && w.b:
+		return -1
+	case v == w:
+		return 0
+	}
+	return 1
+}
+
+// subject is a value that a CEL expression refers to.
+type subject struct {
+	path string
+	typ  types.Type
+}
+
+// extractor turns CEL expressions into atoms.
+type extractor struct {
+	env *cel.Env
+}
+
+func newExtractor() (*extractor, error) {
+	// Without macros, all() stays a call, rather than being expanded into a comprehension.
+	env, err := cel.NewEnv(cel.ClearMacros())
+	if err != nil {
+		return nil, err
+	}
+	return &extractor{env: env}, nil
+}
+
+// atoms returns the atoms of expr, with 'self' being self. whole reports whether expr is
+// exactly their conjunction; otherwise it has conditions that are not understood.
+func (x *extractor) atoms(expr string, self subject) (atoms []atom, whole bool) {
+	parsed, issues := x.env.Parse(expr)
+	if issues.Err() != nil {
+		return nil, false
+	}
+	s := &scope{vars: map[string]subject{"self": self}}
+	return s.conjuncts(parsed.NativeRep().Expr())
+}
+
+// scope binds the variables of an expression to the values they refer to.
+type scope struct {
+	vars map[string]subject
+	keys map[string]subject // The maps that the variables range over the keys of.
+}
+
+func (s *scope) with(name string, v subject, keysOf *subject) *scope {
+	inner := &scope{vars: make(map[string]subject), keys: make(map[string]subject)}
+	for k, v := range s.vars {
+		inner.vars[k] = v
+	}
+	for k, v := range s.keys {
+		inner.keys[k] = v
+	}
+	inner.vars[name] = v
+	if keysOf != nil {
+		inner.keys[name] = *keysOf
+	} else {
+		delete(inner.keys, name)
+	}
+	return inner
+}
+
+func (s *scope) conjuncts(e celast.Expr) ([]atom, bool) {
+	if e.Kind() == celast.CallKind && e.AsCall().FunctionName() == operators.LogicalAnd {
+		args := e.AsCall().Args()
+		left, lwhole := s.conjuncts(args[0])
+		right, rwhole := s.conjuncts(args[1])
+		return append(left, right...), lwhole && rwhole
+	}
+	if atoms, ok := s.all(e); ok {
+		return atoms, true
+	}
+	if a, ok := s.atom(e); ok {
+		return []atom{a}, true
+	}
+	return nil, false
+}
+
+// all returns the atoms of "c.all(x, body)" on the items of c, if body is understood.
+func (s *scope) all(e celast.Expr) ([]atom, bool) {
+	if e.Kind() != celast.CallKind {
+		return nil, false
+	}
+	call := e.AsCall()
+	if call.FunctionName() != "all" || !call.IsMemberFunction() || len(call.Args()) != 2 || call.Args()[0].Kind() != celast.IdentKind {
+		return nil, false
+	}
+	c, ok := s.subject(call.Target())
+	if !ok {
+		return nil, false
+	}
+	var inner *scope
+	switch u := deref(c.typ).Underlying().(type) {
+	case *types.Slice:
+		inner = s.with(call.Args()[0].AsIdent(), subject{c.path + "[]", u.Elem()}, nil)
+	case *types.Array:
+		inner = s.with(call.Args()[0].AsIdent(), subject{c.path + "[]", u.Elem()}, nil)
+	case *types.Map:
+		// Macros on maps range over the keys; the values are reached by indexing.
+		inner = s.with(call.Args()[0].AsIdent(), subject{c.path + "[key]", u.Key()}, &c)
+	default:
+		return nil, false
+	}
+	return inner.conjuncts(call.Args()[1])
+}
+
+// atom returns the condition e, if it is understood.
+func (s *scope) atom(e celast.Expr) (atom, bool) {
+	if v, ok := s.subject(e); ok && kindOf(v.typ) == boolValue {
+		return atom{path: v.path, typ: v.typ, op: operators.Equals, val: value{kind: boolValue, b: true}}, true
+	}
+	if e.Kind() != celast.CallKind {
+		return atom{}, false
+	}
+	call := e.AsCall()
+	args := call.Args()
+	switch fn := call.FunctionName(); fn {
+	case operators.LogicalNot:
+		if v, ok := s.subject(args[0]); ok && kindOf(v.typ) == boolValue {
+			return atom{path: v.path, typ: v.typ, op: operators.Equals, val: value{kind: boolValue, b: false}}, true
+		}
+	case operators.Equals, operators.NotEquals, operators.Less, operators.LessEquals, operators.Greater, operators.GreaterEquals:
+		if a, ok := s.comparison(fn, args[0], args[1]); ok {
+			return a, true
+		}
+		return s.comparison(flip[fn], args[1], args[0])
+	case operators.In:
+		v, size, ok := s.operand(args[0])
+		if !ok || args[1].Kind() != celast.ListKind {
+			break
+		}
+		var set []value
+		for _, elem := range args[1].AsList().Elements() {
+			c, ok := literal(elem)
+			if !ok || !compatible(v.typ, size, c) {
+				return atom{}, false
+			}
+			set = append(set, c)
+		}
+		return atom{path: v.path, typ: v.typ, size: size, op: "in", set: set}, true
+	case overloads.Matches:
+		if !call.IsMemberFunction() || len(args) != 1 {
+			break
+		}
+		v, ok := s.subject(call.Target())
+		pattern, isLit := literal(args[0])
+		if !ok || !isLit || pattern.kind != stringValue || kindOf(v.typ) != stringValue {
+			break
+		}
+		re, err := regexp.Compile(pattern.str)
+		if err != nil {
+			break
+		}
+		return atom{path: v.path, typ: v.typ, op: "matches", re: re}, true
+	}
+	return atom{}, false
+}
+
+// flip maps comparison operators to the operators with their operands swapped.
+var flip = map[string]string{
+	operators.Equals:        operators.Equals,
+	operators.NotEquals:     operators.NotEquals,
+	operators.Less:          operators.Greater,
+	operators.LessEquals:    operators.GreaterEquals,
+	operators.Greater:       operators.Less,
+	operators.GreaterEquals: operators.LessEquals,
+}
+
+// comparison returns the atom for "left op right", where left is a value and right a constant.
+func (s *scope) comparison(op string, left, right celast.Expr) (atom, bool) {
+	v, size, ok := s.operand(left)
+	if !ok {
+		return atom{}, false
+	}
+	if right.Kind() == celast.LiteralKind && right.AsLiteral() == celtypes.NullValue && !size {
+		switch op {
+		case operators.Equals:
+			return atom{path: v.path, typ: v.typ, op: "null"}, true
+		case operators.NotEquals:
+			return atom{path: v.path, typ: v.typ, op: "notnull"}, true
+		}
+		return atom{}, false
+	}
+	c, ok := literal(right)
+	if !ok || !compatible(v.typ, size, c) {
+		return atom{}, false
+	}
+	return atom{path: v.path, typ: v.typ, size: size, op: op, val: c}, true
+}
+
+// operand returns the value e refers to, or whose size it is, as in "self.size()" and "size(self)".
+func (s *scope) operand(e celast.Expr) (v subject, size bool, ok bool) {
+	if e.Kind() == celast.CallKind && e.AsCall().FunctionName() == overloads.Size {
+		call := e.AsCall()
+		switch {
+		case call.IsMemberFunction() && len(call.Args()) == 0:
+			v, ok = s.subject(call.Target())
+		case !call.IsMemberFunction() && len(call.Args()) == 1:
+			v, ok = s.subject(call.Args()[0])
+		}
+		return v, true, ok && sized(v.typ)
+	}
+	v, ok = s.subject(e)
+	return v, false, ok
+}
+
+// subject returns the value e refers to: a variable, a field of a value, or a value of a map.
+func (s *scope) subject(e celast.Expr) (subject, bool) {
+	switch e.Kind() {
+	case celast.IdentKind:
+		v, ok := s.vars[e.AsIdent()]
+		return v, ok
+	case celast.SelectKind:
+		sel := e.AsSelect()
+		if sel.IsTestOnly() {
+			return subject{}, false
+		}
+		v, ok := s.subject(sel.Operand())
+		if !ok {
+			return subject{}, false
+		}
+		return fieldOf(v, sel.FieldName())
+	case celast.CallKind:
+		call := e.AsCall()
+		if call.FunctionName() != operators.Index || call.Args()[1].Kind() != celast.IdentKind {
+			return subject{}, false
+		}
+		m, ok := s.subject(call.Args()[0])
+		keysOf, isKey := s.keys[call.Args()[1].AsIdent()]
+		if !ok || !isKey || keysOf.path != m.path {
+			return subject{}, false
+		}
+		if u, ok := deref(m.typ).Underlying().(*types.Map); ok {
+			return subject{m.path + "[value]", u.Elem()}, true
+		}
+	}
+	return subject{}, false
+}
+
+// fieldOf returns the field name of v. The path of a field promoted from an embedded struct
+// goes through the embedded field, as the rules of the embedded struct apply to it there.
+func fieldOf(v subject, name string) (subject, bool) {
+	obj, index, _ := types.LookupFieldOrMethod(deref(v.typ), false, nil, name)
+	if field, ok := obj.(*types.Var); !ok || !field.IsField() {
+		return subject{}, false
+	}
+	path, t := v.path, deref(v.typ)
+	for _, i := range index {
+		f := t.Underlying().(*types.Struct).Field(i)
+		path, t = path+"."+f.Name(), deref(f.Type())
+	}
+	return subject{path, obj.Type()}, true
+}
+
+// literal returns the constant e, such as 18, -1, 2.5 or "admin".
+func literal(e celast.Expr) (value, bool) {
+	if e.Kind() == celast.CallKind && e.AsCall().FunctionName() == operators.Negate {
+		v, ok := literal(e.AsCall().Args()[0])
+		if !ok || v.kind != numberValue {
+			return value{}, false
+		}
+		return number(-v.num), true
+	}
+	if e.Kind() != celast.LiteralKind {
+		return value{}, false
+	}
+	switch v := e.AsLiteral().(type) {
+	case celtypes.Int:
+		return number(float64(v)), true
+	case celtypes.Uint:
+		return number(float64(v)), true
+	case celtypes.Double:
+		return number(float64(v)), true
+	case celtypes.String:
+		return value{kind: stringValue, str: string(v)}, true
+	case celtypes.Bool:
+		return value{kind: boolValue, b: bool(v)}, true
+	}
+	return value{}, false
+}
+
+// compatible reports whether values of type t, or their sizes, can be compared with c.
+func compatible(t types.Type, size bool, c value) bool {
+	if size {
+		return c.kind == numberValue
+	}
+	return kindOf(t) == c.kind
+}
+
+// kindOf returns the kind of the constants that values of type t are compared with, or 0.
+func kindOf(t types.Type) valueKind {
+	if t == nil {
+		return 0
+	}
+	basic, ok := deref(t).Underlying().(*types.Basic)
+	switch {
+	case !ok:
+		return 0
+	case basic.Info()&types.IsNumeric != 0 && basic.Info()&types.IsComplex == 0:
+		return numberValue
+	case basic.Info()&types.IsString != 0:
+		return stringValue
+	case basic.Info()&types.IsBoolean != 0:
+		return boolValue
+	}
+	return 0
+}
+
+// sized reports whether values of type t have a size: strings, slices and maps.
+func sized(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch u := deref(t).Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&types.IsString != 0
+	case *types.Slice, *types.Array, *types.Map:
+		return true
+	}
+	return false
+}
+
+// isInteger reports whether values of type t are integers, and whether they are unsigned.
+func isInteger(t types.Type) (integer, unsigned bool) {
+	if t == nil {
+		return false, false
+	}
+	basic, ok := deref(t).Underlying().(*types.Basic)
+	if !ok {
+		return false, false
+	}
+	return basic.Info()&types.IsInteger != 0, basic.Info()&types.IsUnsigned != 0
+}
+
+func deref(t types.Type) types.Type {
+	if ptr, ok := t.(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return t
+}
@@ -0,0 +1,314 @@
+package satisfiable
+
+import (
+	"math"
+	"regexp/syntax"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/google/cel-go/common/operators"
+)
+
+// satisfiable reports whether some values meet all of atoms. Values are independent of each
+// other, except for a value and its size. It errs on the side of true: atoms it cannot
+// reason about, such as two patterns, are assumed to be compatible.
+func satisfiable(atoms []atom) bool {
+	var paths []string
+	byPath := make(map[string][]atom)
+	for _, a := range atoms {
+		if _, ok := byPath[a.path]; !ok {
+			paths = append(paths, a.path)
+		}
+		byPath[a.path] = append(byPath[a.path], a)
+	}
+	for _, path := range paths {
+		if !valueSatisfiable(byPath[path]) {
+			return false
+		}
+	}
+	return true
+}
+
+// valueSatisfiable reports whether some value meets all of atoms, which are about the same value.
+func valueSatisfiable(atoms []atom) bool {
+	typ := atoms[0].typ
+	var null, notNull bool
+	var sizeAtoms, valueAtoms []atom
+	for _, a := range atoms {
+		switch {
+		case a.op == "null":
+			null = true
+		case a.op == "notnull":
+			notNull = true
+		case a.size:
+			sizeAtoms = append(sizeAtoms, a)
+		default:
+			valueAtoms = append(valueAtoms, a)
+		}
+	}
+	if null {
+		// Comparing null with anything but null fails.
+		return !notNull && len(sizeAtoms) == 0 && len(valueAtoms) == 0
+	}
+
+	sizes := &valueSet{integer: true}
+	sizes.lowerBound(number(0), false)
+	for _, a := range sizeAtoms {
+		sizes.add(a)
+	}
+
+	integer, unsigned := isInteger(typ)
+	values := &valueSet{integer: integer}
+	if unsigned {
+		values.lowerBound(number(0), false)
+	}
+	if kindOf(typ) == boolValue {
+		values.restrict([]value{{kind: boolValue, b: false}, {kind: boolValue, b: true}})
+	}
+	var patterns []atom
+	for _, a := range valueAtoms {
+		if a.op != "matches" {
+			values.add(a)
+			continue
+		}
+		patterns = append(patterns, a)
+		lo, hi := matchLength(a.re.String())
+		sizes.lowerBound(number(float64(lo)), false)
+		if hi >= 0 {
+			sizes.upperBound(number(float64(hi)), false)
+		}
+	}
+
+	// Strings must also have a size and match the patterns.
+	fits := func(v value) bool {
+		if v.kind != stringValue {
+			return true
+		}
+		if !sizes.contains(number(float64(utf8.RuneCountInString(v.str)))) {
+			return false
+		}
+		for _, p := range patterns {
+			if !p.re.MatchString(v.str) {
+				return false
+			}
+		}
+		return true
+	}
+	return values.satisfiable(fits) && sizes.satisfiable(nil)
+}
+
+// bound is a lower or upper bound of a valueSet.
+type bound struct {
+	v    value
+	open bool // Whether v itself is excluded.
+}
+
+// valueSet is the set of values that meet comparisons with constants.
+type valueSet struct {
+	integer    bool
+	lo, hi     *bound
+	only       []value // If restricted, the values in the set are among these.
+	restricted bool
+	not        []value
+}
+
+func (s *valueSet) add(a atom) {
+	switch a.op {
+	case operators.Equals:
+		s.restrict([]value{a.val})
+	case "in":
+		s.restrict(a.set)
+	case operators.NotEquals:
+		s.not = append(s.not, a.val)
+	case operators.Less:
+		s.upperBound(a.val, true)
+	case operators.LessEquals:
+		s.upperBound(a.val, false)
+	case operators.Greater:
+		s.lowerBound(a.val, true)
+	case operators.GreaterEquals:
+		s.lowerBound(a.val, false)
+	}
+}
+
+func (s *valueSet) restrict(values []value) {
+	if !s.restricted {
+		s.only, s.restricted = slices.Clone(values), true
+		return
+	}
+	s.only = slices.DeleteFunc(s.only, func(v value) bool { return !slices.Contains(values, v) })
+}
+
+func (s *valueSet) lowerBound(v value, open bool) {
+	if s.lo == nil || v.compare(s.lo.v) > 0 || v == s.lo.v && open {
+		s.lo = &bound{v, open}
+	}
+}
+
+func (s *valueSet) upperBound(v value, open bool) {
+	if s.hi == nil || v.compare(s.hi.v) < 0 || v == s.hi.v && open {
+		s.hi = &bound{v, open}
+	}
+}
+
+// contains reports whether v is within the bounds and not excluded.
+func (s *valueSet) contains(v value) bool {
+	switch {
+	case s.integer && v.kind == numberValue && v.num != math.Trunc(v.num):
+		return false
+	case s.lo != nil && (v.compare(s.lo.v) < 0 || v == s.lo.v && s.lo.open):
+		return false
+	case s.hi != nil && (v.compare(s.hi.v) > 0 || v == s.hi.v && s.hi.open):
+		return false
+	}
+	return !slices.Contains(s.not, v)
+}
+
+// satisfiable reports whether the set has a value for which fits, if not nil, holds.
+func (s *valueSet) satisfiable(fits func(value) bool) bool {
+	if s.restricted {
+		return slices.ContainsFunc(s.only, func(v value) bool {
+			return s.contains(v) && (fits == nil || fits(v))
+		})
+	}
+	if s.lo == nil || s.hi == nil {
+		return true
+	}
+	if s.integer && s.lo.v.kind == numberValue {
+		lo, hi := math.Ceil(s.lo.v.num), math.Floor(s.hi.v.num)
+		if s.lo.open && lo == s.lo.v.num {
+			lo++
+		}
+		if s.hi.open && hi == s.hi.v.num {
+			hi--
+		}
+		if hi < lo {
+			return false
+		}
+		if hi-lo+1 > float64(len(s.not)) {
+			return true // More integers than excluded values.
+		}
+		for n := lo; n <= hi; n++ {
+			if s.contains(number(n)) {
+				return true
+			}
+		}
+		return false
+	}
+	switch c := s.lo.v.compare(s.hi.v); {
+	case c > 0:
+		return false
+	case c == 0:
+		return !s.lo.open && !s.hi.open && !slices.Contains(s.not, s.lo.v)
+	}
+	return true
+}
+
+// matchLength returns the least number of characters of the strings that match pattern, as
+// in CEL's matches(), and the most, or -1 if there is no limit. A match may be anywhere in a
+// string, so there is a limit only if pattern is anchored at both ends.
+func matchLength(pattern string) (lo, hi int) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0, -1
+	}
+	re = re.Simplify()
+	lo, hi = length(re)
+	anchored := re.Op == syntax.OpConcat && len(re.Sub) >= 2 &&
+		re.Sub[0].Op == syntax.OpBeginText && re.Sub[len(re.Sub)-1].Op == syntax.OpEndText
+	if !anchored {
+		hi = -1
+	}
+	return lo, hi
+}
+
+// length returns the least and the most number of characters that re matches, or -1 for no limit.
+func length(re *syntax.Regexp) (lo, hi int) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune), len(re.Rune)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1, 1
+	case syntax.OpCapture:
+		return length(re.Sub[0])
+	case syntax.OpStar:
+		return 0, -1
+	case syntax.OpPlus:
+		lo, _ := length(re.Sub[0])
+		return lo, -1
+	case syntax.OpQuest:
+		_, hi := length(re.Sub[0])
+		return 0, hi
+	case syntax.OpRepeat:
+		lo, hi := length(re.Sub[0])
+		if re.Max == -1 || hi == -1 {
+			return lo * re.Min, -1
+		}
+		return lo * re.Min, hi * re.Max
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			l, h := length(sub)
+			lo += l
+			if hi == -1 || h == -1 {
+				hi = -1
+			} else {
+				hi += h
+			}
+		}
+		return lo, hi
+	case syntax.OpAlternate:
+		lo, hi = length(re.Sub[0])
+		for _, sub := range re.Sub[1:] {
+			l, h := length(sub)
+			lo = min(lo, l)
+			if hi != -1 && (h == -1 || h > hi) {
+				hi = h
+			}
+		}
+		return lo, hi
+	}
+	// Empty matches, anchors and word boundaries.
+	return 0, 0
+}
+
+// negate returns the atoms whose conjunction is the negation of a, if there are any.
+func negate(a atom) ([]atom, bool) {
+	n := a
+	switch a.op {
+	case "matches":
+		return nil, false
+	case "in":
+		atoms := make([]atom, len(a.set))
+		for i, v := range a.set {
+			atoms[i] = atom{path: a.path, typ: a.typ, size: a.size, op: operators.NotEquals, val: v}
+		}
+		return atoms, true
+	case "null":
+		n.op = "notnull"
+	case "notnull":
+		n.op = "null"
+	default:
+		n.op = negated[a.op]
+	}
+	return []atom{n}, true
+}
+
+var negated = map[string]string{
+	operators.Equals:        operators.NotEquals,
+	operators.NotEquals:     operators.Equals,
+	operators.Less:          operators.GreaterEquals,
+	operators.LessEquals:    operators.Greater,
+	operators.Greater:       operators.LessEquals,
+	operators.GreaterEquals: operators.Less,
+}
+
+// implies reports whether atoms imply every one of b.
+func implies(atoms []atom, b []atom) bool {
+	for _, a := range b {
+		neg, ok := negate(a)
+		if !ok || satisfiable(append(slices.Clip(atoms), neg...)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package a
+
+type Account struct {
+	Name   string         `validate:"cel:self.size() >= 10,cel:self.size() <= 5"`        // want `Account: field Name: "cel:self.size\(\) >= 10" and "cel:self.size\(\) <= 5" can never both pass`
+	Code   string         `validate:"cel:self.size() == 3,email"`                        // want `Account: field Code: "cel:self.size\(\) == 3" and "email" can never both pass`
+	Role   string         `validate:"cel:self in ['admin', 'user'],cel:self == 'guest'"` // want `Account: field Role: "cel:self in \['admin', 'user'\]" and "cel:self == 'guest'" can never both pass`
+	Age    int            `validate:"cel:self > 5 && self < 6"`                          // want `Account: field Age: "cel:self > 5 && self < 6" can never pass`
+	Ok     bool           `validate:"cel:self,cel:!self"`                                // want `Account: field Ok: "cel:self" and "cel:!self" can never both pass`
+	Tags   []string       `validate:"dive,cel:self.size() > 3 && self.size() < 2"`       // want `Account: field Tags: "dive,cel:self.size\(\) > 3 && self.size\(\) < 2" can never pass`
+	Counts map[string]int `validate:"values,cel:self > 10,cel:self < 3"`                 // want `Account: field Counts: "values,cel:self > 10,cel:self < 3" can never pass`
+	Limit  int            `validate:"cel:self > 0"`
+	Note   string         `validate:"cel:self.startsWith('x') && self.size() >= 0"` // Not understood as a whole.
+}
+
+type Profile struct {
+	Score uint   `validate:"cel:self >= 0u"`              // want `Profile: field Score: "cel:self >= 0u" always passes`
+	Email string `validate:"nonzero,email"`               // want `Profile: field Email: "nonzero" is redundant, as it is implied by "email"`
+	Level int    `validate:"cel:self >= 1,cel:self >= 3"` // want `Profile: field Level: "cel:self >= 1" is redundant, as it is implied by "cel:self >= 3"`
+	Nick  string `validate:"omitempty,nonzero"`           // want `Profile: field Nick: "nonzero" always passes, as omitempty skips the zero value`
+	Bio   string `validate:"omitempty,cel:self.size() <= 100"`
+}
+
+// Rules of the struct are checked with the rules of its fields.
+//
+// @cel: self.Quantity < 1
+type Order struct {
+	Quantity int `validate:"cel:self >= 1"` // want `Order: field Quantity: "self.Quantity < 1" \(on the struct\) and "cel:self >= 1" can never both pass`
+	// The rules of an omitempty field do not apply to its zero value.
+	Coupon string `validate:"omitempty,nonzero"`              // want `Order: field Coupon: "nonzero" always passes, as omitempty skips the zero value`
+	Status string `validate:"cel:self in ['open', 'closed']"` /* want `Order: field Status: "self != 'pending'" is redundant, as it is implied by "cel:self in \['open', 'closed'\]"` */ // @cel: self != 'pending'
+}
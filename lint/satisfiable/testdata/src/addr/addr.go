@@ -0,0 +1,14 @@
+package addr
+
+// @cel: self.size() == 3
+type Code string
+
+// @cel: self.Zip.size() == 5
+type Address struct {
+	City string `validate:"nonzero"`
+	Zip  string
+}
+
+type Item struct {
+	Price int `validate:"cel:self < 50"`
+}
@@ -0,0 +1,20 @@
+package b
+
+import "addr"
+
+// The rules of the types of fields apply to them too, wherever the types are declared.
+//
+/* want `Customer: "self.Home.City == \\"\\"" and "self != \\"\\"" \(on addr.Address.City\) can never both pass` */ // @cel: self.Home.City == ""
+/* want `Customer: "self.Home.Zip.size\(\) > 0" is redundant, as it is implied by "self.Zip.size\(\) == 5" \(on addr.Address\)` */ // @cel: self.Home.Zip.size() > 0
+type Customer struct {
+	Home   addr.Address
+	Region addr.Code   `validate:"cel:self.size() > 5"`       // want `Customer: field Region: "cel:self.size\(\) > 5" and "self.size\(\) == 3" \(on addr.Code\) can never both pass`
+	Items  []addr.Item `validate:"dive,cel:self.Price > 100"` // want `Customer: field Items: "dive,cel:self.Price > 100" and "self < 50" \(on addr.Item.Price\) can never both pass`
+}
+
+// Promoted fields are constrained by the rules of the embedded struct.
+//
+/* want `Office: "self.City != \\"\\"" is redundant, as it is implied by "self != \\"\\"" \(on addr.Address.City\)` */ // @cel: self.City != ""
+type Office struct {
+	addr.Address
+}
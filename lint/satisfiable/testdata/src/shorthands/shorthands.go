@@ -0,0 +1,26 @@
+// Package shorthands has the conflicts that the analyzer finds in the shorthands of validate tags.
+// There are no min, max, len or oneof shorthands (the veritas analyzer reports them as unknown);
+// lengths, ranges and lists are cel: rules, as in package a.
+package shorthands
+
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusInactive Status = "inactive"
+)
+
+type Signup struct {
+	Name   string            `validate:"nonzero,cel:self == ''"`       // want `Signup: field Name: "nonzero" and "cel:self == ''" can never both pass`
+	Count  int               `validate:"nonzero,cel:self == 0"`        // want `Signup: field Count: "nonzero" and "cel:self == 0" can never both pass`
+	Ok     bool              `validate:"nonzero,cel:!self"`            // want `Signup: field Ok: "nonzero" and "cel:!self" can never both pass`
+	Ref    *string           `validate:"required,cel:self == null"`    // want `Signup: field Ref: "required" and "cel:self == null" can never both pass`
+	Tags   []string          `validate:"nonzero,cel:self.size() == 0"` // want `Signup: field Tags: "nonzero" and "cel:self.size\(\) == 0" can never both pass`
+	Items  []string          `validate:"dive,nonzero,cel:self == ''"`  // want `Signup: field Items: "dive,nonzero,cel:self == ''" can never pass`
+	Labels map[string]string `validate:"keys,nonzero,cel:self == ''"`  // want `Signup: field Labels: "keys,nonzero,cel:self == ''" can never pass`
+	Email  string            `validate:"email,cel:self.size() < 3"`    // want `Signup: field Email: "email" and "cel:self.size\(\) < 3" can never both pass`
+	Status Status            `validate:"enum,cel:self == 'banned'"`    // want `Signup: field Status: "enum" and "cel:self == 'banned'" can never both pass`
+	Backup string            `validate:"nonzero,email"`                // want `Signup: field Backup: "nonzero" is redundant, as it is implied by "email"`
+	Limit  *int              `validate:"omitempty,required"`           // want `Signup: field Limit: "required" always passes, as omitempty skips the zero value`
+	Nick   string            `validate:"omitempty,email"`
+}
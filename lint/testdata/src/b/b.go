@@ -1,5 +1,5 @@
 package b
 
-type Product struct { // want "field Price in rules for Product does not exist in struct"
+type Product struct { // want "field Price in rules for b.Product does not exist in struct"
 	Name string
 }
@@ -1,5 +1,5 @@
 package c
 
-type Order struct { // want "invalid field rule for Order.Amount: ERROR: <input>:1:7: Syntax error: mismatched input '<EOF>' expecting .*"
+type Order struct { // want `invalid field rule for c.Order.Amount: rule "self >" does not compile: Syntax error: mismatched input '<EOF>' expecting .*`
 	Amount int
 }
@@ -0,0 +1,19 @@
+package d
+
+import "time"
+
+type Address struct {
+	City string `validate:"nonzero"`
+}
+
+/* want `User: rule "self.Agee >= 18" does not compile: undefined field 'Agee'` */ // @cel: self.Agee >= 18
+type User struct {
+	Name   string            `validate:"cel:self > 1"` // want `User: field Name: rule "self > 1" does not compile: found no matching overload for '_>_' applied to '\(string, int\)'`
+	Nick   string            `validate:"required"`     // Reported by the required analyzer.
+	Broken string            `validate:"cel:f(1"`      // want `User: field Broken: validate tag: column 6: unclosed '\(' in CEL expression`
+	Born   time.Time         `validate:"cel:self < timestamp('2020-01-01T00:00:00Z')"`
+	Tags   map[string]string `validate:"values,nonzero"`
+	Age    int               `validate:"cel:isAdult(self)"` // Functions may be registered at runtime.
+
+	Address *Address /* want `User: field Address: rule "self.Cty != \\"\\"" does not compile: undefined field 'Cty'` */ // @cel: self.Cty != ""
+}
@@ -0,0 +1,11 @@
+package e
+
+type Login struct {
+	Email    string            `validate:"emial"` // want `Login: field Email: validate tag: column 1: unknown validation shorthand "emial" \(did you mean email\?\)`
+	Password string            `validate:"nonzero"`
+	Confirm  string            `validate:"nonzero,eqfield=Passwd"`          // want `Login: field Confirm: validate tag: column 9: eqfield refers to unknown field "Passwd"`
+	Nick     string            `validate:"required"`                        // Reported by the required analyzer.
+	Labels   map[string]string `validate:"dive,keys,email,endkeys,nonzero"` // want `Login: field Labels: validate tag: column 1: 'dive' on non-slice type: map\[string\]string \(use keys and values for maps\)`
+	Scores   map[string]int    `validate:"dive,nonzero"`                    // want `Login: field Scores: validate tag: column 1: 'dive' on non-slice type: map\[string\]int \(use keys and values for maps\)`
+	Roles    []string          `validate:"dive,nonzer"`                     // want `Login: field Roles: validate tag: column 6: unknown validation shorthand "nonzer" \(did you mean nonzero\?\)`
+}
@@ -0,0 +1,5 @@
+package f
+
+type User struct {
+	Name string `validate:"required"`
+}
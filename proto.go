@@ -0,0 +1,222 @@
+package veritas
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/podhmo/veritas/internal/fieldpath"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// protoEnv holds the CEL environments for a single protobuf message type.
+type protoEnv struct {
+	objectEnv *cel.Env // 'self' is the message itself, for type rules.
+	fieldEnv  *cel.Env // 'self' is dynamic, for field rules; the message types are still known.
+}
+
+// protoEnvCache caches protoEnvs by message full name.
+// Unlike native types, message types are discovered lazily during validation, so access is guarded.
+type protoEnvCache struct {
+	mu   sync.Mutex
+	envs map[protoreflect.FullName]*protoEnv
+}
+
+// getProtoEnv creates and caches the CEL environments for the type of msg.
+// The message's file descriptor (and its dependencies) are registered, so that nested
+// messages, repeated fields, maps and well-known types are handled natively by CEL.
+func (v *Validator) getProtoEnv(msg proto.Message) (*protoEnv, error) {
+	name := msg.ProtoReflect().Descriptor().FullName()
+
+	v.protoEnvs.mu.Lock()
+	defer v.protoEnvs.mu.Unlock()
+	if env, ok := v.protoEnvs.envs[name]; ok {
+		return env, nil
+	}
+
+	v.logger.Debug("creating new protobuf CEL environment for message", "message", name)
+
+	baseOpts := make([]cel.EnvOption, len(v.engine.baseOpts))
+	copy(baseOpts, v.engine.baseOpts)
+	baseOpts = append(baseOpts, cel.StdLib(), cel.Types(msg))
+
+	objectEnv, err := cel.NewEnv(append(baseOpts, cel.Variable("self", cel.ObjectType(string(name))))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create protobuf CEL environment for %s: %w", name, err)
+	}
+	fieldEnv, err := cel.NewEnv(append(baseOpts, cel.Variable("self", cel.DynType))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create protobuf CEL environment for %s: %w", name, err)
+	}
+
+	env := &protoEnv{objectEnv: objectEnv, fieldEnv: fieldEnv}
+	if v.protoEnvs.envs == nil {
+		v.protoEnvs.envs = make(map[protoreflect.FullName]*protoEnv)
+	}
+	v.protoEnvs.envs[name] = env
+	return env, nil
+}
+
+// validateProto validates a protobuf message against the rule set keyed by its full name
+// (e.g. "example.v1.User"), then recurses into nested messages, repeated fields and maps.
+// Field rules are keyed by proto field name, or by oneof name to validate whichever member is set.
+// path is the location of msg relative to the validated object, and is reported in errors.
+func (v *Validator) validateProto(ctx context.Context, msg proto.Message, path string, allErrors *[]error) {
+	select {
+	case <-ctx.Done():
+		*allErrors = append(*allErrors, ctx.Err())
+		return
+	default:
+	}
+
+	m := msg.ProtoReflect()
+	if !m.IsValid() {
+		return // A typed nil message.
+	}
+	desc := m.Descriptor()
+	typeName := string(desc.FullName())
+
+	if ruleSet, ok := v.rules[typeName]; ok && v.runsCEL() {
+		v.applyProtoRules(ctx, msg, typeName, ruleSet, path, allErrors)
+	}
+	v.runHooks(ctx, msg, typeName, path, allErrors)
+
+	// Recurse into set fields that hold messages.
+	m.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		fieldPath := fieldpath.Join(path, string(fd.Name()))
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				return true
+			}
+			value.Map().Range(func(key protoreflect.MapKey, elem protoreflect.Value) bool {
+				v.validateProto(ctx, elem.Message().Interface(), fmt.Sprintf("%s[%v]", fieldPath, key.Interface()), allErrors)
+				return true
+			})
+		case fd.IsList():
+			if fd.Message() == nil {
+				return true
+			}
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				v.validateProto(ctx, list.Get(i).Message().Interface(), fmt.Sprintf("%s[%d]", fieldPath, i), allErrors)
+			}
+		case fd.Message() != nil:
+			v.validateProto(ctx, value.Message().Interface(), fieldPath, allErrors)
+		}
+		return true
+	})
+}
+
+func (v *Validator) applyProtoRules(ctx context.Context, msg proto.Message, typeName string, ruleSet ValidationRuleSet, path string, allErrors *[]error) {
+	env, err := v.getProtoEnv(msg)
+	if err != nil {
+		v.logger.Error("failed to get protobuf env", "type", typeName, "error", err)
+		*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("env creation error for %s: %s", typeName, err)))
+		return
+	}
+
+	self := env.objectEnv.CELTypeAdapter().NativeToValue(msg)
+	for _, rule := range ruleSet.TypeRules {
+		v.evalProtoRule(ctx, env.objectEnv, rule, self, typeName, "", path, allErrors)
+	}
+	for fieldName, rules := range ruleSet.CrossFieldRules {
+		for _, rule := range rules {
+			v.evalProtoRule(ctx, env.objectEnv, rule, self, typeName, fieldName, fieldpath.Join(path, fieldName), allErrors)
+		}
+	}
+
+	m := msg.ProtoReflect()
+	desc := m.Descriptor()
+	for fieldName, rules := range ruleSet.FieldRules {
+		fd, isSet := v.protoField(m, fieldName)
+		if fd == nil {
+			v.missingField(typeName, fieldName, "protobuf message", allErrors)
+			continue
+		}
+
+		fieldPath := fieldpath.Join(path, string(fd.Name()))
+		var fieldVal ref.Val
+		switch {
+		case !isSet && fd.ContainingOneof() != nil:
+			// An unset oneof member, or a oneof with no member set, is null.
+			fieldVal = types.NullValue
+			if desc.Oneofs().ByName(protoreflect.Name(fieldName)) != nil {
+				fieldPath = fieldpath.Join(path, fieldName)
+			}
+		case !isSet && fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			// An unset message field is null, rather than CEL's default instance.
+			fieldVal = types.NullValue
+		default:
+			if indexer, ok := self.(traits.Indexer); ok {
+				fieldVal = indexer.Get(types.String(fd.Name()))
+			} else {
+				// Well-known wrapper types are unwrapped by CEL, so read the field directly.
+				fieldVal = env.fieldEnv.CELTypeAdapter().NativeToValue(m.Get(fd))
+			}
+		}
+
+		for _, rule := range rules {
+			v.evalProtoRule(ctx, env.fieldEnv, rule, fieldVal, typeName, fieldName, fieldPath, allErrors)
+		}
+	}
+}
+
+// protoField resolves a field rule key to a field descriptor and reports whether the field is set.
+// The key is a proto field name, a JSON field name, or a oneof name (resolved to the member that is set).
+func (v *Validator) protoField(m protoreflect.Message, key string) (protoreflect.FieldDescriptor, bool) {
+	desc := m.Descriptor()
+	if oneof := desc.Oneofs().ByName(protoreflect.Name(key)); oneof != nil {
+		if fd := m.WhichOneof(oneof); fd != nil {
+			return fd, true
+		}
+		return oneof.Fields().Get(0), false
+	}
+
+	fd := desc.Fields().ByName(protoreflect.Name(key))
+	if fd == nil {
+		fd = desc.Fields().ByJSONName(key)
+	}
+	if fd == nil {
+		return nil, false
+	}
+	return fd, m.Has(fd)
+}
+
+func (v *Validator) evalProtoRule(ctx context.Context, env *cel.Env, rule string, self ref.Val, typeName, fieldName, path string, allErrors *[]error) {
+	prog, err := v.engine.getProgram(env, rule, v.costLimit)
+	if err != nil {
+		v.logger.Error("failed to compile rule (protobuf)", "rule", rule, "type", typeName, "field", fieldName, "error", err)
+		if fieldName == "" {
+			*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("type rule compilation error for %s: %s", typeName, err)))
+		} else {
+			*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("field rule compilation error for %s.%s: %s", typeName, fieldName, err)))
+		}
+		return
+	}
+
+	out, _, err := prog.ContextEval(ctx, map[string]any{"self": self})
+	if err != nil {
+		v.logger.Error("failed to evaluate rule (protobuf)", "rule", rule, "type", typeName, "field", fieldName, "error", err)
+		*allErrors = append(*allErrors, v.evalError(path, typeName, fieldName, rule, err))
+		return
+	}
+
+	if valid, ok := out.Value().(bool); !ok || !valid {
+		*allErrors = append(*allErrors, newValidationErrorAt(path, typeName, fieldName, rule))
+	}
+}
+
+func newValidationErrorAt(path, typeName, fieldName, rule string) error {
+	return &ValidationError{
+		TypeName:  typeName,
+		FieldName: fieldName,
+		Rule:      rule,
+		Path:      path,
+	}
+}
@@ -0,0 +1,190 @@
+package veritas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+
+	"github.com/google/cel-go/test/proto3pb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestValidator_Validate_Proto(t *testing.T) {
+	rules := map[string]ValidationRuleSet{
+		"google.expr.proto3.test.TestAllTypes": {
+			TypeRules: []string{"self.single_int32 <= self.single_int64"},
+			FieldRules: map[string][]string{
+				"single_string":         {"self.size() > 0"},
+				"repeated_string":       {"self.all(x, x != '')"},
+				"map_string_string":     {"self.all(k, k.startsWith('k'))"},
+				"nested_type":           {"self != null"},
+				"single_string_wrapper": {"self == null || self.size() < 5"},
+			},
+		},
+		"google.expr.proto3.test.TestAllTypes.NestedMessage": {
+			FieldRules: map[string][]string{
+				"bb": {"self > 0"},
+			},
+		},
+	}
+
+	v, err := NewValidator(
+		WithRuleProvider(&mapRuleProvider{rules: rules}),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+
+	valid := func() *proto3pb.TestAllTypes {
+		return &proto3pb.TestAllTypes{
+			SingleInt32:     1,
+			SingleInt64:     2,
+			SingleString:    "foo",
+			RepeatedString:  []string{"a", "b"},
+			MapStringString: map[string]string{"k1": "v1"},
+			NestedType: &proto3pb.TestAllTypes_SingleNestedMessage{
+				SingleNestedMessage: &proto3pb.TestAllTypes_NestedMessage{Bb: 1},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *proto3pb.TestAllTypes)
+		want   []string // "path: rule"
+	}{
+		{
+			name:   "valid",
+			modify: func(m *proto3pb.TestAllTypes) {},
+		},
+		{
+			name: "type rule",
+			modify: func(m *proto3pb.TestAllTypes) {
+				m.SingleInt32 = 3
+			},
+			want: []string{": self.single_int32 <= self.single_int64"},
+		},
+		{
+			name: "scalar, repeated and map fields",
+			modify: func(m *proto3pb.TestAllTypes) {
+				m.SingleString = ""
+				m.RepeatedString = []string{"a", ""}
+				m.MapStringString = map[string]string{"x": "v"}
+			},
+			want: []string{
+				"map_string_string: self.all(k, k.startsWith('k'))",
+				"repeated_string: self.all(x, x != '')",
+				"single_string: self.size() > 0",
+			},
+		},
+		{
+			name: "unset oneof",
+			modify: func(m *proto3pb.TestAllTypes) {
+				m.NestedType = nil
+			},
+			want: []string{"nested_type: self != null"},
+		},
+		{
+			name: "oneof member is validated recursively",
+			modify: func(m *proto3pb.TestAllTypes) {
+				m.NestedType = &proto3pb.TestAllTypes_SingleNestedMessage{
+					SingleNestedMessage: &proto3pb.TestAllTypes_NestedMessage{Bb: 0},
+				}
+			},
+			want: []string{"single_nested_message.bb: self > 0"},
+		},
+		{
+			name: "other oneof member",
+			modify: func(m *proto3pb.TestAllTypes) {
+				m.NestedType = &proto3pb.TestAllTypes_SingleNestedEnum{SingleNestedEnum: proto3pb.TestAllTypes_BAR}
+			},
+		},
+		{
+			name: "wrapper field",
+			modify: func(m *proto3pb.TestAllTypes) {
+				m.SingleStringWrapper = wrapperspb.String("too long")
+			},
+			want: []string{"single_string_wrapper: self == null || self.size() < 5"},
+		},
+		{
+			name: "repeated messages",
+			modify: func(m *proto3pb.TestAllTypes) {
+				m.RepeatedNestedMessage = []*proto3pb.TestAllTypes_NestedMessage{{Bb: 1}, {Bb: 0}}
+			},
+			want: []string{"repeated_nested_message[1].bb: self > 0"},
+		},
+		{
+			name: "map of messages",
+			modify: func(m *proto3pb.TestAllTypes) {
+				payload := valid()
+				payload.SingleString = ""
+				m.MapInt64NestedType = map[int64]*proto3pb.NestedTestAllTypes{
+					7: {Payload: payload},
+				}
+			},
+			want: []string{"map_int64_nested_type[7].payload.single_string: self.size() > 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := valid()
+			tt.modify(msg)
+
+			err := v.Validate(context.Background(), msg)
+			var got []string
+			for _, ve := range ValidationErrors(err) {
+				got = append(got, ve.Path+": "+ve.Rule)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Validate() mismatch (-want +got):\n%s\nerr: %v", diff, err)
+			}
+			var fatal *FatalError
+			if errors.As(err, &fatal) {
+				t.Errorf("Validate() returned a fatal error: %v", err)
+			}
+		})
+	}
+
+	t.Run("nested proto in a Go struct", func(t *testing.T) {
+		type request struct {
+			Message *proto3pb.TestAllTypes
+			Items   []*proto3pb.TestAllTypes
+		}
+		msg := valid()
+		msg.SingleString = ""
+		item := valid()
+		item.NestedType = &proto3pb.TestAllTypes_SingleNestedMessage{
+			SingleNestedMessage: &proto3pb.TestAllTypes_NestedMessage{Bb: 0},
+		}
+		err := v.Validate(context.Background(), &request{Message: msg, Items: []*proto3pb.TestAllTypes{valid(), item}})
+		// Paths are relative to the Go struct, through the proto field names of the messages.
+		if diff := cmp.Diff([]*ValidationError{{
+			TypeName:  "google.expr.proto3.test.TestAllTypes",
+			FieldName: "single_string",
+			Rule:      "self.size() > 0",
+			Path:      "Message.single_string",
+		}, {
+			TypeName:  "google.expr.proto3.test.TestAllTypes.NestedMessage",
+			FieldName: "bb",
+			Rule:      "self > 0",
+			Path:      "Items[1].single_nested_message.bb",
+		}}, ValidationErrors(err)); diff != "" {
+			t.Errorf("Validate() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+type mapRuleProvider struct {
+	rules map[string]ValidationRuleSet
+}
+
+func (p *mapRuleProvider) GetRuleSets() (map[string]ValidationRuleSet, error) {
+	return p.rules, nil
+}
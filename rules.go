@@ -9,6 +9,11 @@ import (
 type ValidationRuleSet struct {
 	TypeRules  []string            `json:"typeRules"`
 	FieldRules map[string][]string `json:"fieldRules"`
+
+	// CrossFieldRules are type rules ('self' is the whole object) keyed by field name.
+	// They compare a field with its siblings (e.g. "self.Password == self.PasswordConfirm"),
+	// and their failures are reported against the keyed field rather than the type.
+	CrossFieldRules map[string][]string `json:"crossFieldRules,omitempty"`
 }
 
 // RuleProvider is the interface for any component that can supply validation rules.
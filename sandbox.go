@@ -0,0 +1,264 @@
+package veritas
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+)
+
+// SandboxProfile restricts what rules from an untrusted source (e.g. a JSON file edited by the
+// tenants of a service) may do. Use it with NewSandboxedRuleProvider, so that such rules are
+// rejected when they are loaded, while trusted rules (e.g. generated ones, from the registry)
+// keep the full environment of the Engine.
+//
+// A zero value of a limit means no limit.
+type SandboxProfile struct {
+	// Functions are the functions and macros that rules may call, by name (e.g. "size", "all",
+	// "has", "custom.matches"). Operators are always allowed. If nil, any function may be called.
+	Functions []string
+
+	// MaxRegexComplexity is the maximum size of the regular expressions that rules match with
+	// `matches` and `custom.matches`, as the number of instructions of the compiled expression,
+	// which the time of matching a string is proportional to. The patterns must be constants.
+	MaxRegexComplexity int
+
+	// MaxDepth is the maximum nesting depth of the expression of a rule.
+	MaxDepth int
+
+	// MaxLength is the maximum length of a rule, in characters.
+	MaxLength int
+}
+
+// DefaultSandboxProfile returns a profile for rules that only check the values of fields:
+// comparisons, sizes, string tests, `has` and the `all` and `exists` macros.
+func DefaultSandboxProfile() SandboxProfile {
+	return SandboxProfile{
+		Functions: []string{
+			"size", "has", "all", "exists",
+			"contains", "startsWith", "endsWith", "matches", "custom.matches",
+			"int", "uint", "double", "string",
+		},
+		MaxRegexComplexity: 200,
+		MaxDepth:           32,
+		MaxLength:          1000,
+	}
+}
+
+// SandboxError reports a rule that a SandboxProfile does not allow.
+type SandboxError struct {
+	TypeName  string
+	FieldName string
+	Rule      string
+
+	// Problems are the reasons why the rule is not allowed (e.g. `calls "map", which is not allowed`).
+	Problems []string
+}
+
+func (e *SandboxError) Error() string {
+	where := e.TypeName
+	if e.FieldName != "" {
+		where += "." + e.FieldName
+	}
+	return fmt.Sprintf("%s: rule %s is not allowed: %s", where, e.Rule, strings.Join(e.Problems, "; "))
+}
+
+// NewSandboxedRuleProvider wraps provider so that GetRuleSets fails, with a SandboxError for
+// each rule that profile does not allow, instead of returning the rules.
+func NewSandboxedRuleProvider(provider RuleProvider, profile SandboxProfile) RuleProvider {
+	return &sandboxedProvider{provider: provider, profile: profile}
+}
+
+type sandboxedProvider struct {
+	provider RuleProvider
+	profile  SandboxProfile
+}
+
+// GetRuleSets returns the rule sets of the wrapped provider, if the profile allows all of their rules.
+func (p *sandboxedProvider) GetRuleSets() (map[string]ValidationRuleSet, error) {
+	rules, err := p.provider.GetRuleSets()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.profile.Check(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Check returns a SandboxError for each rule of rules that the profile does not allow, joined.
+func (p SandboxProfile) Check(rules map[string]ValidationRuleSet) error {
+	var errs []error
+	check := func(typeName, fieldName, rule string) {
+		if problems := p.problems(rule); len(problems) > 0 {
+			errs = append(errs, &SandboxError{TypeName: typeName, FieldName: fieldName, Rule: rule, Problems: problems})
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(rules)) {
+		ruleSet := rules[key]
+		for _, rule := range ruleSet.TypeRules {
+			check(key, "", rule)
+		}
+		for _, fieldRules := range []map[string][]string{ruleSet.CrossFieldRules, ruleSet.FieldRules} {
+			for _, fieldName := range slices.Sorted(maps.Keys(fieldRules)) {
+				for _, rule := range fieldRules[fieldName] {
+					check(key, fieldName, rule)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sandboxEnv parses rules with their macros kept as calls, so that macros are checked as functions.
+var sandboxEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(cel.ClearMacros())
+})
+
+// problems returns the reasons why the profile does not allow rule, if any.
+func (p SandboxProfile) problems(rule string) []string {
+	var problems []string
+	if n := utf8.RuneCountInString(rule); p.MaxLength > 0 && n > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("is %d characters long, over the maximum of %d", n, p.MaxLength))
+	}
+
+	env, err := sandboxEnv()
+	if err != nil {
+		return append(problems, fmt.Sprintf("cannot be parsed: %s", err))
+	}
+	parsed, issues := env.Parse(rule)
+	if issues != nil && issues.Err() != nil {
+		return append(problems, fmt.Sprintf("does not parse: %s", issues.Err()))
+	}
+
+	w := &sandboxWalker{profile: p, reported: make(map[string]bool)}
+	if depth := w.walk(parsed.NativeRep().Expr(), nil); p.MaxDepth > 0 && depth > p.MaxDepth {
+		w.problems = append(w.problems, fmt.Sprintf("is nested %d deep, over the maximum of %d", depth, p.MaxDepth))
+	}
+	return append(problems, w.problems...)
+}
+
+// sandboxWalker collects the problems of an expression.
+type sandboxWalker struct {
+	profile  SandboxProfile
+	problems []string
+	reported map[string]bool // Functions that are already reported.
+}
+
+// comprehensions are the macros that bind a variable with their first argument.
+var comprehensions = []string{"all", "exists", "exists_one", "map", "filter"}
+
+// walk checks e, where vars are the variables bound by enclosing macros, and returns its depth.
+// Problems are collected in the order of the source.
+func (w *sandboxWalker) walk(e ast.Expr, vars []string) int {
+	depth := 0
+	walk := func(children ...ast.Expr) {
+		for _, child := range children {
+			depth = max(depth, w.walk(child, vars))
+		}
+	}
+	switch e.Kind() {
+	case ast.CallKind:
+		call := e.AsCall()
+		name := call.FunctionName()
+		args := call.Args()
+		if call.IsMemberFunction() {
+			// A call on a qualified name that is not a variable is a call of a namespaced function.
+			qualified, ok := qualifiedName(call.Target())
+			if root, _, _ := strings.Cut(qualified, "."); ok && root != "self" && !slices.Contains(vars, root) {
+				name = qualified + "." + name
+			} else {
+				walk(call.Target())
+				if slices.Contains(comprehensions, name) && len(args) > 0 && args[0].Kind() == ast.IdentKind {
+					vars = append(slices.Clip(vars), args[0].AsIdent())
+				}
+			}
+		}
+		w.checkCall(name, args)
+		walk(args...)
+	case ast.SelectKind:
+		walk(e.AsSelect().Operand())
+	case ast.ListKind:
+		walk(e.AsList().Elements()...)
+	case ast.MapKind:
+		for _, entry := range e.AsMap().Entries() {
+			walk(entry.AsMapEntry().Key(), entry.AsMapEntry().Value())
+		}
+	case ast.StructKind:
+		for _, field := range e.AsStruct().Fields() {
+			walk(field.AsStructField().Value())
+		}
+	}
+	return depth + 1
+}
+
+// checkCall checks a call of the function name with args.
+func (w *sandboxWalker) checkCall(name string, args []ast.Expr) {
+	if _, ok := operators.FindReverse(name); ok {
+		return
+	}
+	if w.profile.Functions != nil && !slices.Contains(w.profile.Functions, name) && !w.reported[name] {
+		w.reported[name] = true
+		w.problems = append(w.problems, fmt.Sprintf("calls %q, which is not allowed", name))
+	}
+	if w.profile.MaxRegexComplexity > 0 && (name == "matches" || name == "custom.matches") && len(args) > 0 {
+		w.checkPattern(args[len(args)-1])
+	}
+}
+
+// checkPattern checks the complexity of the regular expression e.
+func (w *sandboxWalker) checkPattern(e ast.Expr) {
+	if e.Kind() != ast.LiteralKind || e.AsLiteral().Type() != types.StringType {
+		w.problems = append(w.problems, "matches a pattern that is not a constant, whose complexity cannot be checked")
+		return
+	}
+	pattern := e.AsLiteral().Value().(string)
+	n, err := regexComplexity(pattern)
+	if err != nil {
+		w.problems = append(w.problems, fmt.Sprintf("matches an invalid pattern %q: %s", pattern, err))
+		return
+	}
+	if n > w.profile.MaxRegexComplexity {
+		w.problems = append(w.problems, fmt.Sprintf("matches the pattern %q of complexity %d, over the maximum of %d", pattern, n, w.profile.MaxRegexComplexity))
+	}
+}
+
+// regexComplexity returns the complexity of pattern: the number of instructions of the program
+// that matches it, which the time to match a string is proportional to, per character.
+func regexComplexity(pattern string) (int, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return 0, err
+	}
+	return len(prog.Inst), nil
+}
+
+// qualifiedName returns the dotted name that e is made of (e.g. "custom" or "a.b"), if it is one.
+func qualifiedName(e ast.Expr) (string, bool) {
+	switch e.Kind() {
+	case ast.IdentKind:
+		return e.AsIdent(), true
+	case ast.SelectKind:
+		sel := e.AsSelect()
+		if sel.IsTestOnly() {
+			return "", false
+		}
+		operand, ok := qualifiedName(sel.Operand())
+		return operand + "." + sel.FieldName(), ok
+	}
+	return "", false
+}
@@ -0,0 +1,83 @@
+package veritas
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSandboxProfile_Check(t *testing.T) {
+	profile := DefaultSandboxProfile()
+	cases := []struct {
+		name     string
+		rule     string
+		problems []string
+	}{
+		{name: "comparison", rule: `self.size() > 0 && self.size() <= 100`},
+		{name: "macros", rule: `has(self.Items) && self.Items.all(x, x.startsWith("a") || x.exists(y, y == "b"))`},
+		{name: "namespaced function", rule: `custom.matches(self, "^[a-z]+$")`},
+		{name: "member function on a variable", rule: `self.Items.all(custom, custom.matches("^a"))`},
+		{name: "function not allowed", rule: `self.map(x, x.size()).filter(n, n > 0).size() > 0`, problems: []string{`calls "map", which is not allowed`, `calls "filter", which is not allowed`}},
+		{name: "namespaced function not allowed", rule: `strings.ToUpper(self) == self`, problems: []string{`calls "strings.ToUpper", which is not allowed`}},
+		{name: "complex pattern", rule: `self.matches("^[a-z]{1,100}@[a-z]{1,100}$")`, problems: []string{`matches the pattern "^[a-z]{1,100}@[a-z]{1,100}$" of complexity`}},
+		{name: "pattern not a constant", rule: `self.Email.matches(self.Pattern)`, problems: []string{"matches a pattern that is not a constant"}},
+		{name: "invalid pattern", rule: `self.matches("(a")`, problems: []string{`matches an invalid pattern "(a"`}},
+		{name: "too deep", rule: strings.Repeat("(", 40) + "1 + " + strings.Repeat("1) + ", 39) + "1) > 0", problems: []string{"deep, over the maximum of 32"}},
+		{name: "too long", rule: `self != "` + strings.Repeat("a", 1000) + `"`, problems: []string{"characters long, over the maximum of 1000"}},
+		{name: "syntax error", rule: `self >`, problems: []string{"does not parse"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := profile.Check(map[string]ValidationRuleSet{
+				"pkg.T": {FieldRules: map[string][]string{"F": {c.rule}}},
+			})
+			if len(c.problems) == 0 {
+				if err != nil {
+					t.Errorf("Check() unexpected error: %v", err)
+				}
+				return
+			}
+			var sandboxErr *SandboxError
+			if !errors.As(err, &sandboxErr) {
+				t.Fatalf("Check() error = %v, want a SandboxError", err)
+			}
+			if sandboxErr.TypeName != "pkg.T" || sandboxErr.FieldName != "F" || sandboxErr.Rule != c.rule {
+				t.Errorf("SandboxError = %+v", sandboxErr)
+			}
+			if len(sandboxErr.Problems) != len(c.problems) {
+				t.Fatalf("Problems = %q, want %q", sandboxErr.Problems, c.problems)
+			}
+			for i, want := range c.problems {
+				if !strings.Contains(sandboxErr.Problems[i], want) {
+					t.Errorf("Problems[%d] = %q, want %q", i, sandboxErr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSandboxedRuleProvider(t *testing.T) {
+	rules := []byte(`{
+		"pkg.User": {
+			"typeRules": ["self.Roles.map(r, r.size()).size() > 0"],
+			"fieldRules": {"Name": ["self.size() > 0"]}
+		}
+	}`)
+
+	_, err := NewSandboxedRuleProvider(NewBytesRuleProvider(rules), DefaultSandboxProfile()).GetRuleSets()
+	want := `pkg.User: rule self.Roles.map(r, r.size()).size() > 0 is not allowed: calls "map", which is not allowed`
+	if err == nil || err.Error() != want {
+		t.Errorf("GetRuleSets() error = %v, want %q", err, want)
+	}
+
+	// Without restrictions on functions, the rules are loaded.
+	profile := DefaultSandboxProfile()
+	profile.Functions = nil
+	got, err := NewSandboxedRuleProvider(NewBytesRuleProvider(rules), profile).GetRuleSets()
+	if err != nil {
+		t.Fatalf("GetRuleSets() failed: %v", err)
+	}
+	if len(got["pkg.User"].TypeRules) != 1 {
+		t.Errorf("GetRuleSets() = %v", got)
+	}
+}
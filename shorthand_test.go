@@ -0,0 +1,24 @@
+package veritas_test
+
+import (
+	"go/types"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/podhmo/veritas"
+	"github.com/podhmo/veritas/cmd/veritas/parser"
+	"github.com/podhmo/veritas/validatetag"
+)
+
+// TestEmailRule checks that BenchmarkMatches measures the rule that the "email" shorthand generates.
+func TestEmailRule(t *testing.T) {
+	p := parser.NewParser(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	got, err := p.RuleCEL(&validatetag.Rule{Kind: validatetag.Shorthand, Name: "email"}, types.Typ[types.String])
+	if err != nil {
+		t.Fatalf("RuleCEL() failed: %v", err)
+	}
+	if got != veritas.EmailRule {
+		t.Errorf("the email shorthand generates %s, but the benchmark uses %s", got, veritas.EmailRule)
+	}
+}
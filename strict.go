@@ -0,0 +1,145 @@
+package veritas
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// WithStrict turns configuration problems, which are otherwise logged and skipped, into errors.
+//
+// NewValidator fails if a rule set does not belong to any type the Validator can evaluate, or
+// if the rules of a type registered with WithTypes refer to fields that the type does not have.
+// A rule set belongs to a type registered with WithTypes (or one of its instantiations, for a
+// generic rule set), to the target of a TypeAdapter, to a registered protobuf message, to an
+// anonymous struct field of one of those, or to a named scalar, slice or map type (e.g.
+// `type Email string`) of a field of a struct that is reached from a registered or adapted type.
+//
+// Validate returns a FatalError when it reaches a struct that has rules but is neither registered
+// with WithTypes nor adapted, and when a rule refers to a field that an adapted map or a
+// protobuf message does not have.
+func WithStrict() ValidatorOption {
+	return func(o *validatorOptions) {
+		o.strict = true
+	}
+}
+
+// checkStrict checks the rule sets against the registered types, for WithStrict.
+func (v *Validator) checkStrict() error {
+	var errs []error
+	byName := func(a, b reflect.Type) int { return strings.Compare(a.String(), b.String()) }
+	for _, typ := range slices.SortedFunc(maps.Keys(v.nativeTypes), byName) {
+		typeName := v.getTypeName(typ)
+		ruleSet, ok := v.rules[typeName]
+		if !ok {
+			continue
+		}
+		var missing []string
+		for fieldName := range ruleSet.FieldRules {
+			if !hasField(typ, fieldName) {
+				missing = append(missing, fieldName)
+			}
+		}
+		for fieldName := range ruleSet.CrossFieldRules {
+			if !hasField(typ, fieldName) {
+				missing = append(missing, fieldName)
+			}
+		}
+		if len(missing) > 0 {
+			slices.Sort(missing)
+			errs = append(errs, fmt.Errorf("strict: rules for %s refer to fields that %v does not have: %s", typeName, typ, strings.Join(missing, ", ")))
+		}
+	}
+
+	index := v.ruleIndex()
+	for _, key := range slices.Sorted(maps.Keys(v.rules)) {
+		_, ok, err := v.ruleEnvsOf(key, index)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			errs = append(errs, fmt.Errorf("strict: rules for %s do not belong to any type registered with WithTypes, a TypeAdapter, a protobuf message or a named type of their fields", key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// namedFieldTypes returns the rule-set keys of the named non-struct types (e.g. `type Email string`)
+// of the fields whose rules validateNamedFields applies: the fields of the types registered with
+// WithTypes or adapted, and of the structs reached from them through fields, pointers, slices and maps.
+func (v *Validator) namedFieldTypes() map[string]bool {
+	keys := make(map[string]bool)
+	seen := make(map[reflect.Type]bool)
+	var walk func(typ reflect.Type)
+	walk = func(typ reflect.Type) {
+		// As validateRecursive, reach the structs of fields, of pointers, and of slice elements and map values.
+		if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
+			typ = typ.Elem()
+		}
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct || seen[typ] {
+			return
+		}
+		seen[typ] = true
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldTyp := field.Type
+			if fieldTyp.Kind() == reflect.Ptr {
+				fieldTyp = fieldTyp.Elem()
+			}
+			if isNamedFieldType(fieldTyp) {
+				keys[v.getTypeName(fieldTyp)] = true
+			}
+			walk(field.Type)
+		}
+	}
+	for typ := range v.nativeTypes {
+		walk(typ)
+	}
+	for typ := range v.adapters {
+		walk(typ)
+	}
+	return keys
+}
+
+// isNamedFieldType reports whether the rules of typ, the type of a field or the element type of a
+// pointer field, are applied to the field by validateNamedFields: typ is a named non-struct type.
+func isNamedFieldType(typ reflect.Type) bool {
+	return typ.Name() != "" && typ.Kind() != reflect.Struct && typ.Kind() != reflect.Interface
+}
+
+// anonymousParent returns the rule-set key of the struct that declares the anonymous struct
+// field named by key (e.g. "pkg.User" for "pkg.User.Address"), if key names a field.
+func anonymousParent(key string) (string, bool) {
+	// Only strip field names, never the package path.
+	slash := strings.LastIndex(key, "/")
+	i := strings.LastIndex(key, ".")
+	if i == -1 || i < slash || !strings.Contains(key[slash+1:i], ".") {
+		return "", false
+	}
+	return key[:i], true
+}
+
+// hasField reports whether typ has an exported field, possibly promoted, named name.
+func hasField(typ reflect.Type, name string) bool {
+	field, ok := typ.FieldByName(name)
+	return ok && field.IsExported()
+}
+
+// missingField handles a rule for a field that the object being validated does not have.
+// It is logged, and in strict mode it is also a FatalError.
+func (v *Validator) missingField(typeName, fieldName, where string, allErrors *[]error) {
+	v.logger.Warn("field not found in "+where, "field", fieldName, "type", typeName)
+	if v.strict {
+		*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("rules for %s refer to field %s, which is not found in the %s", typeName, fieldName, where)))
+	}
+}
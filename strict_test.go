@@ -0,0 +1,147 @@
+package veritas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/podhmo/veritas/testdata/sources"
+)
+
+type strictAccount struct {
+	Name     string
+	Discount sources.Percent
+	Address  struct {
+		Zip string
+	}
+	Owner strictOwner
+}
+
+type strictOwner struct {
+	Email string
+}
+
+func TestValidator_WithStrict(t *testing.T) {
+	const pkg = "github.com/podhmo/veritas."
+	newValidator := func(rules map[string]ValidationRuleSet, opts ...ValidatorOption) (*Validator, error) {
+		return NewValidator(append([]ValidatorOption{
+			WithRuleProvider(&mapRuleProvider{rules: rules}),
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		}, opts...)...)
+	}
+	valid := map[string]ValidationRuleSet{
+		pkg + "strictAccount": {
+			FieldRules: map[string][]string{"Name": {`self != ""`}},
+		},
+		pkg + "strictAccount.Address": {
+			FieldRules: map[string][]string{"Zip": {`self != ""`}},
+		},
+		pkg + "strictOwner": {
+			FieldRules: map[string][]string{"Email": {`self != ""`}},
+		},
+		// A named scalar type of a field of a registered type.
+		"github.com/podhmo/veritas/testdata/sources.Percent": {
+			TypeRules: []string{"self >= 0 && self <= 100"},
+		},
+	}
+
+	t.Run("valid configuration", func(t *testing.T) {
+		_, err := newValidator(valid, WithStrict(), WithTypes(strictAccount{}, strictOwner{}))
+		if err != nil {
+			t.Errorf("NewValidator() failed: %v", err)
+		}
+	})
+
+	t.Run("unknown rule keys", func(t *testing.T) {
+		_, err := newValidator(valid, WithStrict(), WithTypes(strictAccount{}))
+		want := "strict: rules for " + pkg + "strictOwner do not belong to any type"
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("NewValidator() error = %v, want %q", err, want)
+		}
+		// Without WithStrict, the rule set is ignored.
+		if _, err := newValidator(valid, WithTypes(strictAccount{})); err != nil {
+			t.Errorf("NewValidator() failed without WithStrict: %v", err)
+		}
+	})
+
+	t.Run("misspelled keys with only type rules", func(t *testing.T) {
+		rules := map[string]ValidationRuleSet{
+			pkg + "strictAccount": {FieldRules: map[string][]string{"Name": {`self != ""`}}},
+			pkg + "strictAcount":  {TypeRules: []string{`self.Name != "root"`}},
+			// Percent is a named type of a field of strictAccount, but Rate is not.
+			"github.com/podhmo/veritas/testdata/sources.Percent": {TypeRules: []string{"self >= 0"}},
+			"github.com/podhmo/veritas/testdata/sources.Rate":    {TypeRules: []string{"self >= 0"}},
+		}
+		_, err := newValidator(rules, WithStrict(), WithTypes(strictAccount{}))
+		for _, key := range []string{pkg + "strictAcount", "github.com/podhmo/veritas/testdata/sources.Rate"} {
+			if want := "strict: rules for " + key + " do not belong to any type"; err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("NewValidator() error = %v, want %q", err, want)
+			}
+		}
+		if err != nil && strings.Contains(err.Error(), "sources.Percent ") {
+			t.Errorf("NewValidator() error = %v, want Percent to be known", err)
+		}
+	})
+
+	t.Run("rules for fields that do not exist", func(t *testing.T) {
+		rules := map[string]ValidationRuleSet{
+			pkg + "strictOwner": {
+				FieldRules:      map[string][]string{"Email": {`self != ""`}, "Phone": {`self != ""`}},
+				CrossFieldRules: map[string][]string{"Fax": {`self.Email != ""`}},
+			},
+		}
+		_, err := newValidator(rules, WithStrict(), WithTypes(strictOwner{}))
+		want := "strict: rules for " + pkg + "strictOwner refer to fields that veritas.strictOwner does not have: Fax, Phone"
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("NewValidator() error = %v, want %q", err, want)
+		}
+	})
+
+	t.Run("types without an evaluation path", func(t *testing.T) {
+		v, err := newValidator(valid, WithStrict(), WithTypes(strictAccount{}), WithTypeAdapters(map[reflect.Type]TypeAdapterTarget{
+			// The adapter makes strictOwner's rules known, but it is not used for strictAccount.Owner.
+			reflect.TypeOf(sources.Item{}): {TargetName: pkg + "strictOwner", Adapter: func(any) (map[string]any, error) { return nil, nil }},
+		}))
+		if err != nil {
+			t.Fatalf("NewValidator() failed: %v", err)
+		}
+		err = v.Validate(context.Background(), strictAccount{Name: "a"})
+		var fatal *FatalError
+		if !errors.As(err, &fatal) || !strings.Contains(fatal.Message, pkg+"strictOwner has rules but cannot be evaluated") {
+			t.Errorf("Validate() error = %v, want a FatalError for strictOwner", err)
+		}
+	})
+
+	t.Run("fields missing from adapted maps", func(t *testing.T) {
+		rules := map[string]ValidationRuleSet{
+			pkg + "strictOwner": {FieldRules: map[string][]string{"Email": {`self != ""`}}},
+		}
+		adapters := map[reflect.Type]TypeAdapterTarget{
+			reflect.TypeOf(strictOwner{}): {TargetName: pkg + "strictOwner", Adapter: func(any) (map[string]any, error) {
+				return map[string]any{"email": "a@example.com"}, nil
+			}},
+		}
+
+		v, err := newValidator(rules, WithTypeAdapters(adapters))
+		if err != nil {
+			t.Fatalf("NewValidator() failed: %v", err)
+		}
+		if err := v.Validate(context.Background(), strictOwner{}); err != nil {
+			t.Errorf("Validate() without WithStrict = %v, want nil", err)
+		}
+
+		v, err = newValidator(rules, WithStrict(), WithTypeAdapters(adapters))
+		if err != nil {
+			t.Fatalf("NewValidator() failed: %v", err)
+		}
+		err = v.Validate(context.Background(), strictOwner{})
+		var fatal *FatalError
+		if !errors.As(err, &fatal) || !strings.Contains(fatal.Message, "refer to field Email, which is not found in the adapted map") {
+			t.Errorf("Validate() error = %v, want a FatalError for Email", err)
+		}
+	})
+}
@@ -0,0 +1,7 @@
+// Package audit is a test package whose structs are embedded by the sources package.
+package audit
+
+type Stamp struct {
+	CreatedBy string `validate:"nonzero"`
+	Revision  int    `validate:"cel:self >= 0"`
+}
@@ -1,6 +1,7 @@
 package sources
 
 // @cel: self.Value != null
+// @veritas:instantiate Box[*Item]
 type Box[T any] struct {
 	Value T `validate:"required"`
 }
@@ -1,6 +1,11 @@
 package sources
 
-import "net/url"
+import (
+	"net/url"
+	"time"
+
+	"github.com/podhmo/veritas/testdata/sources/audit"
+)
 
 // @cel: self.Age >= 18
 // MockUser is a test struct.
@@ -58,6 +63,12 @@ type EmbeddedUser struct {
 	Name string `validate:"nonzero"`
 }
 
+// AuditedUser embeds a struct from another package and a pointer to a struct.
+type AuditedUser struct {
+	audit.Stamp
+	*Base
+}
+
 type ComplexUser struct {
 	Name     string `validate:"nonzero"`
 	Scores   []int  `validate:"cel:self.all(x, x >= 0)"`
@@ -86,3 +97,37 @@ type AnotherUser struct {
 	Username string
 	Email    string
 }
+
+// SignupForm is a struct for testing cross-field comparisons.
+type SignupForm struct {
+	Password        string `validate:"nonzero,eqfield=PasswordConfirm"`
+	PasswordConfirm string
+	StartAt         time.Time
+	EndAt           time.Time `validate:"gtfield=StartAt"`
+}
+
+// Order is a struct for testing conditional requirements.
+type Order struct {
+	Kind      string  `validate:"nonzero"`
+	Company   string  `validate:"required_if=Kind company"`
+	TaxID     *string `validate:"excluded_unless=Kind company"`
+	Phone     string  `validate:"required_without=Email"`
+	Email     string  `validate:"omitempty,email"`
+	Quantity  uint    `validate:"required_with=Note"`
+	Note      string
+	Reference string `validate:"omitempty,nefield=Note,cel:self.startsWith('REF-')"`
+}
+
+// Percent is a percentage.
+// @cel: self >= 0 && self <= 100
+type Percent int
+
+// @cel: self.size() <= 3
+type Tags []string
+
+// Discount is a struct for testing rules inherited from named non-struct types.
+type Discount struct {
+	Rate  Percent
+	Limit *Percent
+	Tags  Tags
+}
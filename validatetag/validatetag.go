@@ -0,0 +1,404 @@
+// Package validatetag parses the `validate` struct tag into a tree of rules.
+//
+// The grammar is:
+//
+//	tag       = rule { "," rule }
+//	rule      = directive | cel | shorthand
+//	directive = ( "dive" | "keys" | "values" ) "," rule { "," rule }
+//	cel       = "cel:" expression
+//	shorthand = name [ "=" arg { " " arg } ]
+//
+// A CEL expression ends at the first comma that is outside of string literals and brackets,
+// so "cel:self.matches('^a,b$')" and "cel:self in ['dive', 'keys']" are single rules.
+// Arguments are separated by spaces and may be quoted with ' or ", e.g. "required_if=Kind 'a, b'".
+//
+// "dive" applies the rules that follow it to each element of a slice. "keys" and "values" apply
+// the rules that follow them, up to the next "keys" or "values", to the keys or values of a map.
+package validatetag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a Rule.
+type Kind int
+
+const (
+	// Shorthand is a named rule such as "nonzero" or "eqfield=Other".
+	Shorthand Kind = iota
+	// CEL is a raw CEL expression, such as "cel:self > 0".
+	CEL
+	// Directive applies its Body to the elements, keys or values of a collection.
+	Directive
+)
+
+// Tag is a parsed `validate` tag.
+type Tag struct {
+	Rules []*Rule
+}
+
+// Rule is a node of the parse tree.
+type Rule struct {
+	Kind Kind
+	Name string   // The shorthand or directive name; "cel" for CEL expressions.
+	Args []string // The unquoted arguments of a shorthand, e.g. ["Kind", "company"].
+	Expr string   // The CEL expression, without the "cel:" prefix.
+	Body []*Rule  // The rules a directive applies to.
+
+	// Pos and End are the byte offsets of the rule in the tag value, End excluded.
+	// For a directive, they only cover the directive name.
+	Pos, End int
+}
+
+// Error is a syntax error in a `validate` tag.
+type Error struct {
+	Offset int // The byte offset in the tag value.
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("validate tag: column %d: %s", e.Offset+1, e.Msg)
+}
+
+// IsDirective reports whether name is a directive ("dive", "keys" or "values").
+func IsDirective(name string) bool {
+	return name == "dive" || name == "keys" || name == "values"
+}
+
+// Parse parses the value of a `validate` tag.
+func Parse(s string) (*Tag, error) {
+	if strings.TrimSpace(s) == "" {
+		return &Tag{}, nil
+	}
+	items, err := scan(s)
+	if err != nil {
+		return nil, err
+	}
+	rules, rest, err := build(items, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, &Error{Offset: rest[0].Pos, Msg: fmt.Sprintf("unexpected %q", rest[0].Name)}
+	}
+	return &Tag{Rules: rules}, nil
+}
+
+// ValueOffset returns the offset of the `validate` value in lit, the source of a struct tag literal
+// including its quotes, so that the offsets of rules in the value can be mapped to the source.
+// ok is false if there is no `validate` key, or if the value is written with escape sequences.
+func ValueOffset(lit string) (offset int, ok bool) {
+	if len(lit) < 2 || lit[0] != '`' {
+		return 0, false // An interpreted string literal escapes the quotes of the value.
+	}
+	tag := lit[1 : len(lit)-1]
+	// The same syntax as reflect.StructTag.Lookup.
+	i := 0
+	for {
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		keyStart := i
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == keyStart || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return 0, false
+		}
+		key := tag[keyStart:i]
+		valueStart := i + 2
+		i = valueStart
+		escaped := false
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				escaped = true
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return 0, false
+		}
+		if key == "validate" {
+			return 1 + valueStart, !escaped
+		}
+		i++
+	}
+}
+
+// build turns the flat list of rules into a tree. Inside the body of keys or values (inMapBody),
+// it stops at the next keys or values, which belongs to the enclosing level.
+func build(items []*Rule, inMapBody bool) ([]*Rule, []*Rule, error) {
+	var rules []*Rule
+	for len(items) > 0 {
+		r := items[0]
+		if r.Kind != Directive {
+			rules = append(rules, r)
+			items = items[1:]
+			continue
+		}
+		if inMapBody && r.Name != "dive" {
+			break
+		}
+
+		var err error
+		if r.Name == "dive" {
+			r.Body, items, err = build(items[1:], false)
+		} else {
+			r.Body, items, err = build(items[1:], true)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(r.Body) == 0 {
+			return nil, nil, &Error{Offset: r.End, Msg: fmt.Sprintf("%s must be followed by rules", r.Name)}
+		}
+		rules = append(rules, r)
+	}
+	return rules, items, nil
+}
+
+// scan splits s into rules, without building the tree.
+func scan(s string) ([]*Rule, error) {
+	var items []*Rule
+	pos := 0
+	for {
+		start := skipSpaces(s, pos)
+		var r *Rule
+		var err error
+		if strings.HasPrefix(s[start:], "cel:") {
+			r, pos, err = scanCEL(s, start)
+		} else {
+			r, pos, err = scanShorthand(s, start)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, r)
+
+		if pos >= len(s) {
+			return items, nil
+		}
+		pos++ // Skip the comma.
+	}
+}
+
+func scanCEL(s string, start int) (*Rule, int, error) {
+	exprStart := start + len("cel:")
+	var stack []int // Offsets of the open brackets.
+	i := exprStart
+	for i < len(s) {
+		switch c := s[i]; c {
+		case '\'', '"':
+			end, err := skipQuoted(s, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			i = end
+			continue
+		case '(', '[', '{':
+			stack = append(stack, i)
+		case ')', ']', '}':
+			if len(stack) == 0 || s[stack[len(stack)-1]] != opening(c) {
+				return nil, 0, &Error{Offset: i, Msg: fmt.Sprintf("unexpected %q in CEL expression", c)}
+			}
+			stack = stack[:len(stack)-1]
+		case ',':
+			if len(stack) == 0 {
+				return newCELRule(s, start, exprStart, i)
+			}
+		}
+		i++
+	}
+	if len(stack) > 0 {
+		return nil, 0, &Error{Offset: stack[len(stack)-1], Msg: fmt.Sprintf("unclosed %q in CEL expression", s[stack[len(stack)-1]])}
+	}
+	return newCELRule(s, start, exprStart, i)
+}
+
+func newCELRule(s string, start, exprStart, end int) (*Rule, int, error) {
+	expr := strings.TrimSpace(s[exprStart:end])
+	if expr == "" {
+		return nil, 0, &Error{Offset: start, Msg: "empty CEL expression"}
+	}
+	return &Rule{Kind: CEL, Name: "cel", Expr: expr, Pos: start, End: trimEnd(s, start, end)}, end, nil
+}
+
+func opening(c byte) byte {
+	switch c {
+	case ')':
+		return '('
+	case ']':
+		return '['
+	default:
+		return '{'
+	}
+}
+
+func scanShorthand(s string, start int) (*Rule, int, error) {
+	i := start
+	for i < len(s) && s[i] != ',' {
+		if s[i] == '\'' || s[i] == '"' {
+			end, err := skipQuoted(s, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			i = end
+			continue
+		}
+		i++
+	}
+	end := trimEnd(s, start, i)
+	text := s[start:end]
+	if text == "" {
+		return nil, 0, &Error{Offset: start, Msg: "empty rule"}
+	}
+
+	name, _, hasArgs := strings.Cut(text, "=")
+	if !isName(name) {
+		return nil, 0, &Error{Offset: start, Msg: fmt.Sprintf("invalid rule name %q", name)}
+	}
+	r := &Rule{Kind: Shorthand, Name: name, Pos: start, End: end}
+	if IsDirective(name) {
+		if hasArgs {
+			return nil, 0, &Error{Offset: start + len(name), Msg: fmt.Sprintf("%s takes no arguments", name)}
+		}
+		r.Kind = Directive
+		return r, i, nil
+	}
+	if hasArgs {
+		args, err := splitArgs(s, start+len(name)+1, end)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(args) == 0 {
+			return nil, 0, &Error{Offset: start + len(name), Msg: fmt.Sprintf("missing arguments after %s=", name)}
+		}
+		r.Args = args
+	}
+	return r, i, nil
+}
+
+// splitArgs splits s[start:end] into space-separated, possibly quoted, arguments.
+func splitArgs(s string, start, end int) ([]string, error) {
+	var args []string
+	i := start
+	for {
+		i = skipSpaces(s[:end], i)
+		if i >= end {
+			return args, nil
+		}
+		if s[i] == '\'' || s[i] == '"' {
+			quoteEnd, err := skipQuoted(s, i)
+			if err != nil {
+				return nil, err
+			}
+			arg, err := unquote(s[i:quoteEnd])
+			if err != nil {
+				return nil, &Error{Offset: i, Msg: fmt.Sprintf("invalid quoted argument: %s", err)}
+			}
+			args = append(args, arg)
+			i = quoteEnd
+			continue
+		}
+		argStart := i
+		for i < end && s[i] != ' ' && s[i] != '\t' {
+			if s[i] == '\'' || s[i] == '"' {
+				return nil, &Error{Offset: i, Msg: "unexpected quote inside an argument"}
+			}
+			i++
+		}
+		args = append(args, s[argStart:i])
+	}
+}
+
+// skipQuoted returns the offset just after the string literal starting at s[start].
+func skipQuoted(s string, start int) (int, error) {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, &Error{Offset: start, Msg: "unterminated string literal"}
+}
+
+func unquote(lit string) (string, error) {
+	if lit[0] == '\'' {
+		// Convert to a double-quoted literal, so that strconv handles the escapes.
+		body := lit[1 : len(lit)-1]
+		body = strings.ReplaceAll(body, `\'`, `'`)
+		body = strings.ReplaceAll(body, `"`, `\"`)
+		lit = `"` + body + `"`
+	}
+	return strconv.Unquote(lit)
+}
+
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func trimEnd(s string, start, end int) int {
+	for end > start && (s[end-1] == ' ' || s[end-1] == '\t') {
+		end--
+	}
+	return end
+}
+
+// String formats the tag in its canonical form.
+func (t *Tag) String() string {
+	var parts []string
+	for _, r := range t.Rules {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// String formats the rule (and, for a directive, its body) in its canonical form.
+func (r *Rule) String() string {
+	switch r.Kind {
+	case CEL:
+		return "cel:" + r.Expr
+	case Directive:
+		parts := []string{r.Name}
+		for _, b := range r.Body {
+			parts = append(parts, b.String())
+		}
+		return strings.Join(parts, ",")
+	default:
+		if len(r.Args) == 0 {
+			return r.Name
+		}
+		args := make([]string, len(r.Args))
+		for i, arg := range r.Args {
+			if arg == "" || strings.ContainsAny(arg, " \t,'\"\\") {
+				arg = strconv.Quote(arg)
+			}
+			args[i] = arg
+		}
+		return r.Name + "=" + strings.Join(args, " ")
+	}
+}
@@ -0,0 +1,159 @@
+package validatetag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want []*Rule
+	}{
+		{
+			name: "shorthands",
+			tag:  "nonzero, email",
+			want: []*Rule{
+				{Kind: Shorthand, Name: "nonzero", Pos: 0, End: 7},
+				{Kind: Shorthand, Name: "email", Pos: 9, End: 14},
+			},
+		},
+		{
+			name: "arguments",
+			tag:  `required_if=Kind 'a, b' "c\"d"`,
+			want: []*Rule{
+				{Kind: Shorthand, Name: "required_if", Args: []string{"Kind", "a, b", `c"d`}, Pos: 0, End: 30},
+			},
+		},
+		{
+			name: "CEL with commas and keywords",
+			tag:  "cel:self in ['dive', 'a,b'] && f(1, 2),nonzero",
+			want: []*Rule{
+				{Kind: CEL, Name: "cel", Expr: "self in ['dive', 'a,b'] && f(1, 2)", Pos: 0, End: 38},
+				{Kind: Shorthand, Name: "nonzero", Pos: 39, End: 46},
+			},
+		},
+		{
+			name: "dive consumes the rest",
+			tag:  "nonzero,dive,dive,nonzero",
+			want: []*Rule{
+				{Kind: Shorthand, Name: "nonzero", Pos: 0, End: 7},
+				{Kind: Directive, Name: "dive", Pos: 8, End: 12, Body: []*Rule{
+					{Kind: Directive, Name: "dive", Pos: 13, End: 17, Body: []*Rule{
+						{Kind: Shorthand, Name: "nonzero", Pos: 18, End: 25},
+					}},
+				}},
+			},
+		},
+		{
+			name: "keys and values",
+			tag:  "keys,cel:self.startsWith('id_'),values,dive,required",
+			want: []*Rule{
+				{Kind: Directive, Name: "keys", Pos: 0, End: 4, Body: []*Rule{
+					{Kind: CEL, Name: "cel", Expr: "self.startsWith('id_')", Pos: 5, End: 31},
+				}},
+				{Kind: Directive, Name: "values", Pos: 32, End: 38, Body: []*Rule{
+					{Kind: Directive, Name: "dive", Pos: 39, End: 43, Body: []*Rule{
+						{Kind: Shorthand, Name: "required", Pos: 44, End: 52},
+					}},
+				}},
+			},
+		},
+		{
+			// Before the shared grammar, a body of shorthands ended at the first cel: rule,
+			// so the rules after it applied to the map itself.
+			name: "keys and values run to the next keys or values",
+			tag:  "keys,nonzero,cel:self.size() < 8,max=3,values,cel:self > 0,nonzero",
+			want: []*Rule{
+				{Kind: Directive, Name: "keys", Pos: 0, End: 4, Body: []*Rule{
+					{Kind: Shorthand, Name: "nonzero", Pos: 5, End: 12},
+					{Kind: CEL, Name: "cel", Expr: "self.size() < 8", Pos: 13, End: 32},
+					{Kind: Shorthand, Name: "max", Args: []string{"3"}, Pos: 33, End: 38},
+				}},
+				{Kind: Directive, Name: "values", Pos: 39, End: 45, Body: []*Rule{
+					{Kind: CEL, Name: "cel", Expr: "self > 0", Pos: 46, End: 58},
+					{Kind: Shorthand, Name: "nonzero", Pos: 59, End: 66},
+				}},
+			},
+		},
+		{
+			name: "empty",
+			tag:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.tag)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got.Rules); diff != "" {
+				t.Errorf("Parse() mismatch (-want +got):\n%s", diff)
+			}
+
+			// The canonical form parses to the same tree, apart from positions.
+			again, err := Parse(got.String())
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", got.String(), err)
+			}
+			if again.String() != got.String() {
+				t.Errorf("String() is not stable: %q != %q", again.String(), got.String())
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "nonzero,,email", want: "validate tag: column 9: empty rule"},
+		{tag: "nonzero,", want: "validate tag: column 9: empty rule"},
+		{tag: "cel:f(1", want: `validate tag: column 6: unclosed '(' in CEL expression`},
+		{tag: "cel:f(1]", want: `validate tag: column 8: unexpected ']' in CEL expression`},
+		{tag: "cel:'abc", want: "validate tag: column 5: unterminated string literal"},
+		{tag: "cel:", want: "validate tag: column 1: empty CEL expression"},
+		{tag: "nonzero,dive", want: "validate tag: column 13: dive must be followed by rules"},
+		{tag: "dive=1,nonzero", want: "validate tag: column 5: dive takes no arguments"},
+		{tag: "no-zero", want: `validate tag: column 1: invalid rule name "no-zero"`},
+		{tag: "eqfield=", want: "validate tag: column 8: missing arguments after eqfield="},
+		{tag: "required_if=Kind a'b'", want: "validate tag: column 19: unexpected quote inside an argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			_, err := Parse(tt.tag)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueOffset(t *testing.T) {
+	tests := []struct {
+		lit    string
+		want   int
+		wantOK bool
+	}{
+		{"`validate:\"nonzero\"`", 11, true},
+		{"`json:\"name\" validate:\"nonzero\"`", 23, true},
+		{"`json:\"a\\\"b\" validate:\"nonzero\"`", 23, true},
+		{"`validate:\"cel:self == \\\"a\\\"\"`", 0, false},
+		{"`json:\"name\"`", 0, false},
+		{"\"validate:\\\"nonzero\\\"\"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ValueOffset(tt.lit)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("ValueOffset(%s) = %d, %v, want %d, %v", tt.lit, got, ok, tt.want, tt.wantOK)
+		}
+		if ok && !strings.HasPrefix(tt.lit[got:], "nonzero") {
+			t.Errorf("ValueOffset(%s) = %d, which is not the start of the value", tt.lit, got)
+		}
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/ext"
+	"github.com/podhmo/veritas/internal/fieldpath"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 // TypeAdapterFunc is the function signature for converting a Go object.
@@ -29,10 +35,15 @@ type Validator struct {
 	objectEnv   *cel.Env // For object-level rules (e.g., self.field > 10)
 	fieldEnv    *cel.Env // For field-level rules (e.g., self.size() > 0)
 	rules       map[string]ValidationRuleSet
+	generics    map[string]string // The generic rule keys by base name, e.g. "pkg.Box" -> "pkg.Box[T]".
 	adapters    map[reflect.Type]TypeAdapterTarget
 	logger      *slog.Logger
 	nativeTypes map[reflect.Type]struct{}
 	nativeEnvs  map[reflect.Type]*cel.Env // Cache for type-specific native environments
+	protoEnvs   protoEnvCache             // Cache for protobuf message environments
+	hookMode    HookMode
+	strict      bool
+	costLimit   uint64
 }
 
 // ValidatorOption is an option for configuring a Validator.
@@ -45,6 +56,11 @@ type validatorOptions struct {
 	adapters    map[reflect.Type]TypeAdapterTarget
 	types       []any
 	nativeTypes map[reflect.Type]struct{}
+	hookMode    HookMode
+	strict      bool
+	costBudget  uint64
+	costMaxSize uint64
+	costLimit   uint64
 }
 
 // WithEngine sets the CEL engine for the validator.
@@ -109,6 +125,14 @@ func NewValidator(opts ...ValidatorOption) (*Validator, error) {
 		options.engine = engine
 	}
 
+	// The cost options of the Validator take precedence over the defaults of the Engine.
+	if options.costBudget == 0 {
+		options.costBudget, options.costMaxSize = options.engine.costBudget, options.engine.costMaxSize
+	}
+	if options.costLimit == 0 {
+		options.costLimit = options.engine.costLimit
+	}
+
 	// Default provider if not provided
 	if options.provider == nil {
 		options.provider = NewRuleProviderFromRegistry()
@@ -152,10 +176,14 @@ func NewValidator(opts ...ValidatorOption) (*Validator, error) {
 		objectEnv:   objectEnv,
 		fieldEnv:    fieldEnv,
 		rules:       rules,
+		generics:    genericIndex(rules),
 		adapters:    options.adapters,
 		logger:      options.logger,
 		nativeTypes: options.nativeTypes,
 		nativeEnvs:  make(map[reflect.Type]*cel.Env),
+		hookMode:    options.hookMode,
+		strict:      options.strict,
+		costLimit:   options.costLimit,
 	}
 
 	// Pre-create native environments for all registered types
@@ -166,9 +194,31 @@ func NewValidator(opts ...ValidatorOption) (*Validator, error) {
 				typ = typ.Elem()
 			}
 			options.nativeTypes[typ] = struct{}{} // Add to options, not v
-			if _, err := v.getNativeEnv(typ); err != nil {
+			env, err := v.getNativeEnv(typ)
+			if err != nil {
 				return nil, fmt.Errorf("failed to create initial native env for %v: %w", typ, err)
 			}
+			if err := v.checkNativeRules(env, typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if v.engine.precompile {
+		if err := v.precompileRules(); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.costBudget != 0 {
+		if err := v.checkCost(options.costBudget, options.costMaxSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if v.strict {
+		if err := v.checkStrict(); err != nil {
+			return nil, err
 		}
 	}
 
@@ -194,7 +244,7 @@ func (v *Validator) Validate(ctx context.Context, obj any) error {
 	}
 
 	// Use a helper function to perform the validation recursively.
-	v.validateRecursive(ctx, obj, &allErrors)
+	v.validateRecursive(ctx, obj, "", "", &allErrors)
 
 	if len(allErrors) > 0 {
 		return errors.Join(allErrors...)
@@ -203,7 +253,8 @@ func (v *Validator) Validate(ctx context.Context, obj any) error {
 }
 
 // getTypeName constructs a full type name string (e.g., "github.com/foo/bar/baz.User") from a reflect.Type.
-// For generic types, it attempts to find a matching generic rule definition (e.g., "...Box[T]").
+// For an instantiation of a generic type (e.g., "Box[string]"), rules registered for the instantiation
+// itself are preferred; otherwise the generic rule definition (e.g., "...Box[T]") is used, if any.
 func (v *Validator) getTypeName(typ reflect.Type) string {
 	name := typ.Name()
 	pkgPath := typ.PkgPath()
@@ -212,25 +263,166 @@ func (v *Validator) getTypeName(typ reflect.Type) string {
 		fullName = fmt.Sprintf("%s.%s", pkgPath, name)
 	}
 
-	// If the type is a generic instantiation (e.g., "Box[string]"),
-	// try to find the corresponding generic rule key (e.g., "...Box[T]").
-	if genericMarkerPos := strings.Index(name, "["); genericMarkerPos != -1 {
-		baseName := name[:genericMarkerPos]
-		fullBaseName := baseName
-		if pkgPath != "" {
-			fullBaseName = fmt.Sprintf("%s.%s", pkgPath, baseName)
+	if _, ok := v.rules[fullName]; ok {
+		return fullName
+	}
+	if base, _, ok := splitGenericKey(fullName); ok {
+		if key, ok := v.generics[base]; ok {
+			return key
 		}
+	}
+	return fullName
+}
 
-		// Search for a rule key that starts with the base name and has a generic marker.
-		for key := range v.rules {
-			if strings.HasPrefix(key, fullBaseName+"[") {
-				return key // Found it, e.g., "github.com/podhmo/veritas/testdata/sources.Box[T]"
+// checkNativeRules compiles the type and cross-field rules of typ in its native environment,
+// so that rules that do not type-check for it (e.g. a generic rule comparing a field to null,
+// for an instantiation where the field is a string) are reported when the Validator is created.
+func (v *Validator) checkNativeRules(env *cel.Env, typ reflect.Type) error {
+	typeName := v.getTypeName(typ)
+	ruleSet, ok := v.rules[typeName]
+	if !ok {
+		return nil
+	}
+	var errs []error
+	for _, rule := range ruleSet.TypeRules {
+		if _, err := v.engine.getProgram(env, rule, v.costLimit); err != nil {
+			errs = append(errs, fmt.Errorf("type rule %q of %s does not compile for %v: %w", rule, typeName, typ, err))
+		}
+	}
+	for fieldName, rules := range ruleSet.CrossFieldRules {
+		for _, rule := range rules {
+			if _, err := v.engine.getProgram(env, rule, v.costLimit); err != nil {
+				errs = append(errs, fmt.Errorf("cross-field rule %q of %s.%s does not compile for %v: %w", rule, typeName, fieldName, typ, err))
 			}
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	// For non-generic types or if no generic rule is found, return the full type name.
-	return fullName
+// precompileRules compiles every rule in the environments that it is evaluated in, for WithPrecompile.
+// Rule sets that do not belong to any type the Validator can evaluate are never evaluated, so they
+// are not compiled; WithStrict reports them.
+func (v *Validator) precompileRules() error {
+	index := v.ruleIndex()
+	var errs []error
+	count := 0
+	compile := func(env *cel.Env, key, fieldName, rule string) {
+		count++
+		if _, err := v.engine.getProgram(env, rule, v.costLimit); err != nil {
+			where := key
+			if fieldName != "" {
+				where += "." + fieldName
+			}
+			errs = append(errs, fmt.Errorf("rule %q of %s does not compile: %w", rule, where, err))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(v.rules)) {
+		envs, ok, err := v.ruleEnvsOf(key, index)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			v.logger.Debug("rules are not precompiled, as they belong to no known type", "key", key)
+			continue
+		}
+		envs.each(key, v.rules[key], compile)
+	}
+	if count > v.engine.cacheSize {
+		v.logger.Warn("the program cache is smaller than the number of precompiled rules", "rules", count, "cache_size", v.engine.cacheSize)
+	}
+	return errors.Join(errs...)
+}
+
+// ruleEnvs are the environments that the rules of a rule set are evaluated in.
+// A nil environment means that the rules of that kind are never evaluated.
+type ruleEnvs struct {
+	object []*cel.Env // For type and cross-field rules; one per instantiation of a generic rule set.
+	field  *cel.Env   // For field rules.
+}
+
+// each calls f for every rule of ruleSet with the environment that it is evaluated in.
+func (e ruleEnvs) each(key string, ruleSet ValidationRuleSet, f func(env *cel.Env, key, fieldName, rule string)) {
+	for _, env := range e.object {
+		for _, rule := range ruleSet.TypeRules {
+			f(env, key, "", rule)
+		}
+		for _, fieldName := range slices.Sorted(maps.Keys(ruleSet.CrossFieldRules)) {
+			for _, rule := range ruleSet.CrossFieldRules[fieldName] {
+				f(env, key, fieldName, rule)
+			}
+		}
+	}
+	if e.field != nil {
+		for _, fieldName := range slices.Sorted(maps.Keys(ruleSet.FieldRules)) {
+			for _, rule := range ruleSet.FieldRules[fieldName] {
+				f(e.field, key, fieldName, rule)
+			}
+		}
+	}
+}
+
+// ruleIndex is what decides how the rules of a rule-set key are evaluated.
+type ruleIndex struct {
+	native  map[string][]reflect.Type // The types registered with WithTypes, by rule-set key.
+	adapted map[string]bool           // The target names of TypeAdapters.
+	named   map[string]bool           // The named types of fields, see namedFieldTypes.
+}
+
+func (v *Validator) ruleIndex() ruleIndex {
+	index := ruleIndex{native: make(map[string][]reflect.Type), adapted: make(map[string]bool), named: v.namedFieldTypes()}
+	for typ := range v.nativeTypes {
+		key := v.getTypeName(typ)
+		index.native[key] = append(index.native[key], typ)
+	}
+	for _, types := range index.native {
+		slices.SortFunc(types, func(a, b reflect.Type) int { return strings.Compare(a.String(), b.String()) })
+	}
+	for _, target := range v.adapters {
+		index.adapted[target.TargetName] = true
+	}
+	return index
+}
+
+// ruleEnvsOf returns the environments that the rules of key are evaluated in, following
+// validateRecursive, and false if they are never evaluated, because key belongs to no type
+// that the Validator can evaluate.
+func (v *Validator) ruleEnvsOf(key string, index ruleIndex) (ruleEnvs, bool, error) {
+	// A struct registered with WithTypes: validateNative.
+	if types, ok := index.native[key]; ok {
+		envs := ruleEnvs{field: v.fieldEnv}
+		for _, typ := range types {
+			env, err := v.getNativeEnv(typ)
+			if err != nil {
+				return ruleEnvs{}, false, err
+			}
+			envs.object = append(envs.object, env)
+		}
+		return envs, true, nil
+	}
+	// The target of a TypeAdapter: validateWithAdapter.
+	if index.adapted[key] {
+		return ruleEnvs{object: []*cel.Env{v.objectEnv}, field: v.fieldEnv}, true, nil
+	}
+	// A protobuf message: validateProto.
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(key)); err == nil {
+		env, err := v.getProtoEnv(mt.New().Interface())
+		if err != nil {
+			return ruleEnvs{}, false, err
+		}
+		return ruleEnvs{object: []*cel.Env{env.objectEnv}, field: env.fieldEnv}, true, nil
+	}
+	// An anonymous struct field of a known struct: validateAnonymous.
+	if parent, ok := anonymousParent(key); ok {
+		if _, ok, _ := v.ruleEnvsOf(parent, index); ok && !index.named[parent] {
+			return ruleEnvs{object: []*cel.Env{v.objectEnv}, field: v.fieldEnv}, true, nil
+		}
+	}
+	// A named type of fields: validateNamedFields, which only applies the type rules.
+	if index.named[key] {
+		return ruleEnvs{object: []*cel.Env{v.fieldEnv}}, true, nil
+	}
+	return ruleEnvs{}, false, nil
 }
 
 // dereferenceAndAdapt handles the crucial step of preparing a value for CEL evaluation.
@@ -310,7 +502,10 @@ func (v *Validator) isNativeType(typ reflect.Type) bool {
 }
 
 // validateRecursive is the internal helper that performs the actual validation.
-func (v *Validator) validateRecursive(ctx context.Context, obj any, allErrors *[]error) {
+// path is the location of obj relative to the validated object (e.g. "Profiles[0]"), and is passed to hooks.
+// anonName is the rule-set key of obj's type when it is an anonymous struct (e.g. "pkg.User.Address"),
+// which is made of the enclosing type's name and the field's name.
+func (v *Validator) validateRecursive(ctx context.Context, obj any, path, anonName string, allErrors *[]error) {
 	// Check for context cancellation before proceeding.
 	select {
 	case <-ctx.Done():
@@ -319,6 +514,12 @@ func (v *Validator) validateRecursive(ctx context.Context, obj any, allErrors *[
 	default:
 	}
 
+	// Protobuf messages are validated natively by CEL, against rules keyed by message full name.
+	if msg, ok := obj.(proto.Message); ok {
+		v.validateProto(ctx, msg, path, allErrors)
+		return
+	}
+
 	// Dereference pointer to get the actual value.
 	val := reflect.ValueOf(obj)
 	if val.Kind() == reflect.Ptr {
@@ -333,20 +534,38 @@ func (v *Validator) validateRecursive(ctx context.Context, obj any, allErrors *[
 		return
 	}
 	typ := val.Type()
+	typeName := v.getTypeName(typ)
+	if typ.Name() == "" {
+		typeName = anonName
+	}
 
 	// Determine which validation path to take for the current object.
-	if v.isNativeType(typ) {
-		v.validateNative(ctx, val.Interface(), typ, allErrors)
-	} else {
+	switch {
+	case !v.runsCEL():
+		// CEL rules are disabled; only hooks are run.
+	case typ.Name() == "":
+		v.validateAnonymous(ctx, val, typeName, path, allErrors)
+	case v.isNativeType(typ):
+		v.validateNative(ctx, val.Interface(), typ, path, allErrors)
+	default:
 		// Default to adapter-based path if not explicitly native.
 		// This handles types with adapters and types with no rules.
-		v.validateWithAdapter(ctx, val.Interface(), typ, allErrors)
+		v.validateWithAdapter(ctx, val.Interface(), typ, path, allErrors)
+	}
+	if v.runsCEL() {
+		v.validateNamedFields(ctx, val, typ, typeName, path, allErrors)
 	}
+	v.runHooks(ctx, obj, typeName, path, allErrors)
 
 	// --- Common Recursive Validation Step for Nested Fields ---
 	// Iterate over the fields of the struct to find nested structs, slices, and maps.
 	for i := 0; i < val.NumField(); i++ {
 		fieldVal := val.Field(i)
+		fieldPath := fieldpath.Join(path, typ.Field(i).Name)
+		fieldAnonName := ""
+		if typeName != "" {
+			fieldAnonName = typeName + "." + typ.Field(i).Name
+		}
 
 		// Ensure we can get an interface to the field to pass to the recursive call.
 		if !fieldVal.CanInterface() {
@@ -355,12 +574,12 @@ func (v *Validator) validateRecursive(ctx context.Context, obj any, allErrors *[
 
 		switch fieldVal.Kind() {
 		case reflect.Struct:
-			v.validateRecursive(ctx, fieldVal.Interface(), allErrors)
+			v.validateRecursive(ctx, fieldVal.Interface(), fieldPath, fieldAnonName, allErrors)
 
 		case reflect.Ptr:
 			// Only recurse on pointers to structs.
 			if !fieldVal.IsNil() && fieldVal.Type().Elem().Kind() == reflect.Struct {
-				v.validateRecursive(ctx, fieldVal.Interface(), allErrors)
+				v.validateRecursive(ctx, fieldVal.Interface(), fieldPath, fieldAnonName, allErrors)
 			}
 
 		case reflect.Slice:
@@ -368,7 +587,7 @@ func (v *Validator) validateRecursive(ctx context.Context, obj any, allErrors *[
 			for j := 0; j < fieldVal.Len(); j++ {
 				elem := fieldVal.Index(j)
 				if elem.CanInterface() {
-					v.validateRecursive(ctx, elem.Interface(), allErrors)
+					v.validateRecursive(ctx, elem.Interface(), fmt.Sprintf("%s[%d]", fieldPath, j), fieldAnonName, allErrors)
 				}
 			}
 
@@ -378,7 +597,7 @@ func (v *Validator) validateRecursive(ctx context.Context, obj any, allErrors *[
 			for iter.Next() {
 				elem := iter.Value()
 				if elem.CanInterface() {
-					v.validateRecursive(ctx, elem.Interface(), allErrors)
+					v.validateRecursive(ctx, elem.Interface(), fmt.Sprintf("%s[%v]", fieldPath, iter.Key()), fieldAnonName, allErrors)
 				}
 			}
 		}
@@ -386,7 +605,7 @@ func (v *Validator) validateRecursive(ctx context.Context, obj any, allErrors *[
 }
 
 // validateNative handles validation using the native CEL environment.
-func (v *Validator) validateNative(ctx context.Context, obj any, typ reflect.Type, allErrors *[]error) {
+func (v *Validator) validateNative(ctx context.Context, obj any, typ reflect.Type, path string, allErrors *[]error) {
 	typeName := v.getTypeName(typ)
 	ruleSet, hasRules := v.rules[typeName]
 	if !hasRules {
@@ -403,7 +622,7 @@ func (v *Validator) validateNative(ctx context.Context, obj any, typ reflect.Typ
 	// Type Rules use the native object directly.
 	objectVars := map[string]any{"self": obj}
 	for _, rule := range ruleSet.TypeRules {
-		prog, err := v.engine.getProgram(nativeEnv, rule)
+		prog, err := v.engine.getProgram(nativeEnv, rule, v.costLimit)
 		if err != nil {
 			v.logger.Error("failed to compile type rule (native)", "rule", rule, "type", typeName, "error", err)
 			*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("type rule compilation error for %s: %s", typeName, err)))
@@ -412,32 +631,64 @@ func (v *Validator) validateNative(ctx context.Context, obj any, typ reflect.Typ
 
 		out, _, err := prog.ContextEval(ctx, objectVars)
 		if err != nil {
-			// This is a workaround. For generic types, a rule like `self.Value != null` can
-			// fail with "no matching overload" if `Value` is a non-nullable type like `string`.
-			// We choose to ignore this specific error, assuming that a field-level rule
-			// will correctly validate the non-null aspect (e.g., `self != ""`).
-			if strings.Contains(err.Error(), "no matching overload") {
-				v.logger.Debug("ignored 'no matching overload' error for generic type rule", "rule", rule, "type", typeName, "error", err)
+			if isNilEmbeddedError(err) {
+				// A promoted field of a nil embedded pointer is absent, so a rule using it cannot hold.
+				v.logger.Debug("rule refers to a field of a nil embedded struct", "rule", rule, "type", typeName, "error", err)
+				*allErrors = append(*allErrors, newValidationErrorAt(path, typeName, "", rule))
 			} else {
 				v.logger.Error("failed to evaluate type rule (native)", "rule", rule, "type", typeName, "error", err)
-				*allErrors = append(*allErrors, NewValidationError(typeName, "", fmt.Sprintf("evaluation error: %s", err)))
+				*allErrors = append(*allErrors, v.evalError(path, typeName, "", rule, err))
 			}
 			continue
 		}
 
 		if valid, ok := out.Value().(bool); !ok || !valid {
-			*allErrors = append(*allErrors, NewValidationError(typeName, "", rule))
+			*allErrors = append(*allErrors, newValidationErrorAt(path, typeName, "", rule))
+		}
+	}
+
+	// Cross-field Rules also use the native object, but failures are reported against a field.
+	for fieldName, rules := range ruleSet.CrossFieldRules {
+		for _, rule := range rules {
+			prog, err := v.engine.getProgram(nativeEnv, rule, v.costLimit)
+			if err != nil {
+				v.logger.Error("failed to compile cross-field rule (native)", "rule", rule, "type", typeName, "field", fieldName, "error", err)
+				*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("cross-field rule compilation error for %s.%s: %s", typeName, fieldName, err)))
+				continue
+			}
+
+			out, _, err := prog.ContextEval(ctx, objectVars)
+			if err != nil {
+				if isNilEmbeddedError(err) {
+					v.logger.Debug("rule refers to a field of a nil embedded struct", "rule", rule, "type", typeName, "field", fieldName, "error", err)
+					*allErrors = append(*allErrors, newValidationErrorAt(fieldpath.Join(path, fieldName), typeName, fieldName, rule))
+					continue
+				}
+				v.logger.Error("failed to evaluate cross-field rule (native)", "rule", rule, "type", typeName, "field", fieldName, "error", err)
+				*allErrors = append(*allErrors, v.evalError(fieldpath.Join(path, fieldName), typeName, fieldName, rule, err))
+				continue
+			}
+
+			if valid, ok := out.Value().(bool); !ok || !valid {
+				*allErrors = append(*allErrors, newValidationErrorAt(fieldpath.Join(path, fieldName), typeName, fieldName, rule))
+			}
 		}
 	}
 
 	// Field Rules are evaluated against the field's value.
 	val := reflect.ValueOf(obj) // We know obj is a struct here
 	for fieldName, rules := range ruleSet.FieldRules {
-		fieldVal := val.FieldByName(fieldName)
-		if !fieldVal.IsValid() {
-			v.logger.Warn("field not found in native struct", "field", fieldName, "type", typeName)
+		structField, ok := typ.FieldByName(fieldName)
+		if !ok {
+			v.missingField(typeName, fieldName, "native struct", allErrors)
 			continue
 		}
+		// A promoted field is reached through its embedded structs, which may be nil pointers.
+		fieldVal, err := val.FieldByIndexErr(structField.Index)
+		if err != nil {
+			v.logger.Debug("field of a nil embedded struct is treated as null", "field", fieldName, "type", typeName)
+			fieldVal = reflect.Zero(reflect.PointerTo(structField.Type))
+		}
 
 		var fieldInterface any
 		if fieldVal.Kind() == reflect.Ptr {
@@ -468,7 +719,7 @@ func (v *Validator) validateNative(ctx context.Context, obj any, typ reflect.Typ
 
 			for _, rule := range rules {
 				// We use the simpler `fieldEnv` for this, as it expects `self` to be dynamic.
-				prog, err := v.engine.getProgram(v.fieldEnv, rule)
+				prog, err := v.engine.getProgram(v.fieldEnv, rule, v.costLimit)
 				if err != nil {
 					v.logger.Error("failed to compile field rule (native)", "rule", rule, "type", typeName, "field", fieldName, "error", err)
 					*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("field rule compilation error for %s.%s: %s", typeName, fieldName, err)))
@@ -480,35 +731,78 @@ func (v *Validator) validateNative(ctx context.Context, obj any, typ reflect.Typ
 					// Check for the specific "unsupported conversion" error and provide a better message.
 					if strings.Contains(err.Error(), "unsupported conversion") {
 						v.logger.Error("unsupported conversion in native field rule", "rule", rule, "type", typeName, "field", fieldName, "value_type", reflect.TypeOf(fieldInterface), "error", err)
-						*allErrors = append(*allErrors, NewValidationError(typeName, fieldName, fmt.Sprintf("unsupported type for native validation: %T", fieldInterface)))
+						*allErrors = append(*allErrors, newValidationErrorAt(fieldpath.Join(path, fieldName), typeName, fieldName, fmt.Sprintf("unsupported type for native validation: %T", fieldInterface)))
 					} else {
 						v.logger.Error("failed to evaluate field rule (native)", "rule", rule, "type", typeName, "field", fieldName, "error", err)
-						*allErrors = append(*allErrors, NewValidationError(typeName, fieldName, fmt.Sprintf("evaluation error: %s", err)))
+						*allErrors = append(*allErrors, v.evalError(fieldpath.Join(path, fieldName), typeName, fieldName, rule, err))
 					}
 					continue
 				}
 
 				if valid, ok := out.Value().(bool); !ok || !valid {
-					*allErrors = append(*allErrors, NewValidationError(typeName, fieldName, rule))
+					*allErrors = append(*allErrors, newValidationErrorAt(fieldpath.Join(path, fieldName), typeName, fieldName, rule))
 				}
 			}
 		}
 	}
 }
 
-// validateWithAdapter handles validation using the adapter-based CEL environment.
-func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflect.Type, allErrors *[]error) {
-	typeName := v.getTypeName(typ)
+// validateNamedFields applies the rules of named non-struct types (e.g. `type Email string`)
+// to the fields of val that have such a type, or a pointer to it. The rules are the type's
+// TypeRules, evaluated with 'self' being the field's value, and failures are reported against the field.
+func (v *Validator) validateNamedFields(ctx context.Context, val reflect.Value, typ reflect.Type, typeName, path string, allErrors *[]error) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldVal := val.Field(i)
+		if fieldVal.Kind() == reflect.Ptr {
+			if fieldVal.IsNil() {
+				continue
+			}
+			fieldVal = fieldVal.Elem()
+		}
+		fieldTyp := fieldVal.Type()
+		if !isNamedFieldType(fieldTyp) {
+			continue
+		}
+		ruleSet, ok := v.rules[v.getTypeName(fieldTyp)]
+		if !ok || len(ruleSet.TypeRules) == 0 {
+			continue
+		}
+
+		fieldVars := map[string]any{"self": fieldVal.Interface()}
+		for _, rule := range ruleSet.TypeRules {
+			prog, err := v.engine.getProgram(v.fieldEnv, rule, v.costLimit)
+			if err != nil {
+				v.logger.Error("failed to compile rule of named type", "rule", rule, "type", fieldTyp, "error", err)
+				*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("type rule compilation error for %s: %s", v.getTypeName(fieldTyp), err)))
+				continue
+			}
+
+			out, _, err := prog.ContextEval(ctx, fieldVars)
+			if err != nil {
+				v.logger.Error("failed to evaluate rule of named type", "rule", rule, "type", fieldTyp, "field", field.Name, "error", err)
+				*allErrors = append(*allErrors, v.evalError(fieldpath.Join(path, field.Name), typeName, field.Name, rule, err))
+				continue
+			}
 
-	// Normalize generic type names for rule lookup.
-	if genericMarkerPos := strings.LastIndex(typeName, "["); genericMarkerPos != -1 {
-		baseName := typeName[:genericMarkerPos]
-		v.logger.Debug("detected generic type", "original", typeName, "base", baseName)
-		if typeSpecName, ok := v.getGenericTypeName(baseName); ok {
-			v.logger.Debug("found matching generic rule", "from", baseName, "to", typeSpecName)
-			typeName = typeSpecName
+			if valid, ok := out.Value().(bool); !ok || !valid {
+				*allErrors = append(*allErrors, newValidationErrorAt(fieldpath.Join(path, field.Name), typeName, field.Name, rule))
+			}
 		}
 	}
+}
+
+// isNilEmbeddedError reports whether err is CEL failing to read a promoted field through a nil embedded pointer.
+func isNilEmbeddedError(err error) bool {
+	return strings.Contains(err.Error(), "nil pointer to embedded struct")
+}
+
+// validateWithAdapter handles validation using the adapter-based CEL environment.
+func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflect.Type, path string, allErrors *[]error) {
+	typeName := v.getTypeName(typ)
 
 	adapterTarget, hasAdapter := v.adapters[typ]
 	ruleSet, hasRules := v.rules[typeName]
@@ -529,6 +823,10 @@ func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflec
 	if hasAdapter {
 		objMap, err = adapterTarget.Adapter(obj)
 	} else {
+		if v.strict {
+			*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("%s has rules but cannot be evaluated: register it with WithTypes or a TypeAdapter", typeName)))
+			return
+		}
 		v.logger.Debug("no TypeAdapter, cannot perform CEL validation, but continuing to recurse", "type", typeName)
 		return
 	}
@@ -539,6 +837,29 @@ func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflec
 		return
 	}
 
+	v.validateMap(ctx, objMap, typeName, path, ruleSet, allErrors)
+}
+
+// validateAnonymous validates an anonymous struct against the rule set registered as typeName.
+// CEL cannot refer to anonymous types, so the struct's exported fields are passed to the
+// rules as a map, as a TypeAdapter would do.
+func (v *Validator) validateAnonymous(ctx context.Context, val reflect.Value, typeName, path string, allErrors *[]error) {
+	ruleSet, ok := v.rules[typeName]
+	if !ok || typeName == "" {
+		return
+	}
+	typ := val.Type()
+	objMap := make(map[string]any, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).IsExported() {
+			objMap[typ.Field(i).Name] = val.Field(i).Interface()
+		}
+	}
+	v.validateMap(ctx, objMap, typeName, path, ruleSet, allErrors)
+}
+
+// validateMap applies ruleSet to an object represented as a map of its fields.
+func (v *Validator) validateMap(ctx context.Context, objMap map[string]any, typeName, path string, ruleSet ValidationRuleSet, allErrors *[]error) {
 	if objMap != nil {
 		// Apply type rules using the objectEnv.
 		adaptedMapForTypeRules := make(map[string]any, len(objMap))
@@ -548,7 +869,7 @@ func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflec
 		objectVars := map[string]any{"self": adaptedMapForTypeRules}
 
 		for _, rule := range ruleSet.TypeRules {
-			prog, err := v.engine.getProgram(v.objectEnv, rule)
+			prog, err := v.engine.getProgram(v.objectEnv, rule, v.costLimit)
 			if err != nil {
 				v.logger.Error("failed to compile type rule", "rule", rule, "type", typeName, "error", err)
 				*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("type rule compilation error for %s: %s", typeName, err)))
@@ -558,12 +879,35 @@ func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflec
 			out, _, err := prog.ContextEval(ctx, objectVars)
 			if err != nil {
 				v.logger.Error("failed to evaluate type rule", "rule", rule, "type", typeName, "error", err)
-				*allErrors = append(*allErrors, NewValidationError(typeName, "", fmt.Sprintf("evaluation error: %s", err)))
+				*allErrors = append(*allErrors, v.evalError(path, typeName, "", rule, err))
 				continue
 			}
 
 			if valid, ok := out.Value().(bool); !ok || !valid {
-				*allErrors = append(*allErrors, NewValidationError(typeName, "", rule))
+				*allErrors = append(*allErrors, newValidationErrorAt(path, typeName, "", rule))
+			}
+		}
+
+		// Apply cross-field rules using the objectEnv, reporting failures against the field.
+		for fieldName, rules := range ruleSet.CrossFieldRules {
+			for _, rule := range rules {
+				prog, err := v.engine.getProgram(v.objectEnv, rule, v.costLimit)
+				if err != nil {
+					v.logger.Error("failed to compile cross-field rule", "rule", rule, "type", typeName, "field", fieldName, "error", err)
+					*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("cross-field rule compilation error for %s.%s: %s", typeName, fieldName, err)))
+					continue
+				}
+
+				out, _, err := prog.ContextEval(ctx, objectVars)
+				if err != nil {
+					v.logger.Error("failed to evaluate cross-field rule", "rule", rule, "type", typeName, "field", fieldName, "error", err)
+					*allErrors = append(*allErrors, v.evalError(fieldpath.Join(path, fieldName), typeName, fieldName, rule, err))
+					continue
+				}
+
+				if valid, ok := out.Value().(bool); !ok || !valid {
+					*allErrors = append(*allErrors, newValidationErrorAt(fieldpath.Join(path, fieldName), typeName, fieldName, rule))
+				}
 			}
 		}
 
@@ -571,7 +915,7 @@ func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflec
 		for fieldName, rules := range ruleSet.FieldRules {
 			fieldVal, ok := objMap[fieldName]
 			if !ok {
-				v.logger.Warn("field not found in adapted map", "field", fieldName, "type", typeName)
+				v.missingField(typeName, fieldName, "adapted map", allErrors)
 				continue
 			}
 
@@ -579,7 +923,7 @@ func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflec
 			fieldVars := map[string]any{"self": adaptedFieldVal}
 
 			for _, rule := range rules {
-				prog, err := v.engine.getProgram(v.fieldEnv, rule)
+				prog, err := v.engine.getProgram(v.fieldEnv, rule, v.costLimit)
 				if err != nil {
 					v.logger.Error("failed to compile field rule", "rule", rule, "type", typeName, "field", fieldName, "error", err)
 					*allErrors = append(*allErrors, NewFatalError(fmt.Sprintf("field rule compilation error for %s.%s: %s", typeName, fieldName, err)))
@@ -589,32 +933,18 @@ func (v *Validator) validateWithAdapter(ctx context.Context, obj any, typ reflec
 				out, _, err := prog.ContextEval(ctx, fieldVars)
 				if err != nil {
 					v.logger.Error("failed to evaluate field rule", "rule", rule, "type", typeName, "field", fieldName, "error", err)
-					*allErrors = append(*allErrors, NewValidationError(typeName, fieldName, fmt.Sprintf("evaluation error: %s", err)))
+					*allErrors = append(*allErrors, v.evalError(fieldpath.Join(path, fieldName), typeName, fieldName, rule, err))
 					continue
 				}
 
 				if valid, ok := out.Value().(bool); !ok || !valid {
-					*allErrors = append(*allErrors, NewValidationError(typeName, fieldName, rule))
+					*allErrors = append(*allErrors, newValidationErrorAt(fieldpath.Join(path, fieldName), typeName, fieldName, rule))
 				}
 			}
 		}
 	}
 }
 
-// getGenericTypeName finds a generic type name from the rules map that matches a base name.
-// For example, given "main.Box", it might find "main.Box[T]".
-func (v *Validator) getGenericTypeName(baseName string) (string, bool) {
-	// This is inefficient but works for the purpose of this library where the number
-	// of rules is not expected to be astronomically large.
-	// A better approach might be to pre-process the rule keys into a more searchable structure.
-	for key := range v.rules {
-		if strings.HasPrefix(key, baseName+"[") {
-			return key, true
-		}
-	}
-	return "", false
-}
-
 // NewValidatorFromJSONFile creates a new validator from a JSON file.
 // It is a convenience function that wraps NewValidator with a JSONRuleProvider.
 func NewValidatorFromJSONFile(filePath string, opts ...ValidatorOption) (*Validator, error) {
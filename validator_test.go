@@ -7,11 +7,14 @@ import (
 	"log/slog"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/podhmo/veritas/testdata/sources"
+	"github.com/podhmo/veritas/testdata/sources/audit"
 )
 
 // mockUserAdapter converts a MockUser object (or a pointer to it) to a map.
@@ -937,7 +940,7 @@ func TestValidator_NewValidatorFromJSONFile_WithTypesAndAdapters(t *testing.T) {
 					Handle:   "gopher",
 				},
 			},
-			wantErr: NewValidationError("github.com/podhmo/veritas/testdata/sources.MockUser", "Email", `self != "" && self.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$')`),
+			wantErr: &ValidationError{TypeName: "github.com/podhmo/veritas/testdata/sources.MockUser", FieldName: "Email", Rule: `self != "" && self.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$')`, Path: "User.Email"},
 		},
 		{
 			name: "native valid, adapter invalid",
@@ -953,7 +956,7 @@ func TestValidator_NewValidatorFromJSONFile_WithTypesAndAdapters(t *testing.T) {
 					Handle:   "go", // <-- adapter validation failure
 				},
 			},
-			wantErr: NewValidationError("sources.Profile", "Handle", `self != "" && self.size() > 2`),
+			wantErr: &ValidationError{TypeName: "sources.Profile", FieldName: "Handle", Rule: `self != "" && self.size() > 2`, Path: "Profile.Handle"},
 		},
 		{
 			name: "both invalid",
@@ -1021,3 +1024,406 @@ func BenchmarkValidator_Validate_Invalid_WithCache(b *testing.B) {
 		_ = validator.Validate(ctx, invalidUser)
 	}
 }
+
+func TestValidator_Validate_CrossFieldRules(t *testing.T) {
+	const typeName = "github.com/podhmo/veritas/testdata/sources.SignupForm"
+	rules := map[string]ValidationRuleSet{
+		typeName: {
+			CrossFieldRules: map[string][]string{
+				"Password": {"self.Password == self.PasswordConfirm"},
+				"EndAt":    {"self.EndAt > self.StartAt"},
+			},
+		},
+	}
+	v, err := NewValidator(
+		WithRuleProvider(&mapRuleProvider{rules: rules}),
+		WithTypes(sources.SignupForm{}),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+	)
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := sources.SignupForm{Password: "secret", PasswordConfirm: "secret", StartAt: start, EndAt: start.Add(time.Hour)}
+	if err := v.Validate(context.Background(), valid); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	invalid := sources.SignupForm{Password: "secret", PasswordConfirm: "secrets", StartAt: start, EndAt: start}
+	err = v.Validate(context.Background(), &invalid)
+	got := ToErrorMap(err)
+	want := map[string]string{
+		"Password": "self.Password == self.PasswordConfirm",
+		"EndAt":    "self.EndAt > self.StartAt",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() errors = %v, want %v", got, want)
+	}
+}
+
+func TestValidator_Validate_ConditionalRules(t *testing.T) {
+	const typeName = "github.com/podhmo/veritas/testdata/sources.Order"
+	// The rules generated for sources.Order.
+	rules := map[string]ValidationRuleSet{
+		typeName: {
+			FieldRules: map[string][]string{
+				"Email":     {`self == "" || (self.matches('^[^\\s@]+@[^\\s@]+\\.[^\\s@]+$'))`},
+				"Reference": {`self == "" || (self.startsWith('REF-'))`},
+			},
+			CrossFieldRules: map[string][]string{
+				"Company":   {`self.Kind != "company" || self.Company != ""`},
+				"TaxID":     {`self.Kind == "company" || (!has(self.TaxID) || dyn(self.TaxID) == null)`},
+				"Phone":     {`self.Email != "" || self.Phone != ""`},
+				"Quantity":  {`self.Note == "" || self.Quantity != 0u`},
+				"Reference": {`self.Reference == "" || (self.Reference != self.Note)`},
+			},
+		},
+	}
+	v, err := NewValidator(
+		WithRuleProvider(&mapRuleProvider{rules: rules}),
+		WithTypes(sources.Order{}),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+	)
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+
+	taxID := "T-1"
+	tests := []struct {
+		name  string
+		order sources.Order
+		want  []string // failing fields
+	}{
+		{
+			name:  "personal order",
+			order: sources.Order{Kind: "personal", Phone: "000"},
+		},
+		{
+			name:  "company order",
+			order: sources.Order{Kind: "company", Company: "ACME", TaxID: &taxID, Email: "a@example.com"},
+		},
+		{
+			name:  "required_if",
+			order: sources.Order{Kind: "company", Phone: "000"},
+			want:  []string{"Company"},
+		},
+		{
+			name:  "excluded_unless",
+			order: sources.Order{Kind: "personal", TaxID: &taxID, Phone: "000"},
+			want:  []string{"TaxID"},
+		},
+		{
+			name:  "required_without",
+			order: sources.Order{Kind: "personal"},
+			want:  []string{"Phone"},
+		},
+		{
+			name:  "required_with",
+			order: sources.Order{Kind: "personal", Phone: "000", Note: "gift"},
+			want:  []string{"Quantity"},
+		},
+		{
+			name:  "omitempty skips rules only when zero",
+			order: sources.Order{Kind: "personal", Email: "invalid", Reference: "X", Note: "X", Quantity: 1},
+			want:  []string{"Email", "Reference", "Reference"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(context.Background(), tt.order)
+			var got []string
+			for _, ve := range ValidationErrors(err) {
+				got = append(got, ve.FieldName)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() failing fields = %v, want %v\nerr: %v", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidator_Validate_NamedTypeRules(t *testing.T) {
+	const pkg = "github.com/podhmo/veritas/testdata/sources."
+	rules := map[string]ValidationRuleSet{
+		pkg + "Percent": {TypeRules: []string{"self >= 0 && self <= 100"}},
+		pkg + "Tags":    {TypeRules: []string{"self.size() <= 3"}},
+	}
+
+	for _, native := range []bool{false, true} {
+		t.Run(fmt.Sprintf("native=%v", native), func(t *testing.T) {
+			opts := []ValidatorOption{
+				WithRuleProvider(&mapRuleProvider{rules: rules}),
+				WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+			}
+			if native {
+				opts = append(opts, WithTypes(sources.Discount{}))
+			}
+			v, err := NewValidator(opts...)
+			if err != nil {
+				t.Fatalf("NewValidator() failed: %v", err)
+			}
+
+			limit := sources.Percent(50)
+			if err := v.Validate(context.Background(), sources.Discount{Rate: 10, Limit: &limit, Tags: sources.Tags{"a"}}); err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+
+			limit = 101
+			err = v.Validate(context.Background(), &sources.Discount{Rate: -1, Limit: &limit, Tags: sources.Tags{"a", "b", "c", "d"}})
+			want := map[string]string{
+				"Rate":  "self >= 0 && self <= 100",
+				"Limit": "self >= 0 && self <= 100",
+				"Tags":  "self.size() <= 3",
+			}
+			if got := ToErrorMap(err); !reflect.DeepEqual(got, want) {
+				t.Errorf("Validate() errors = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidator_Validate_EmbeddedStructs(t *testing.T) {
+	const pkg = "github.com/podhmo/veritas/testdata/sources."
+	rules := map[string]ValidationRuleSet{
+		pkg + "AuditedUser": {
+			TypeRules: []string{`self.ID != self.CreatedBy`},
+			FieldRules: map[string][]string{
+				"CreatedBy": {`self != ""`},
+				"ID":        {`self != null`},
+			},
+		},
+	}
+	v, err := NewValidator(
+		WithRuleProvider(&mapRuleProvider{rules: rules}),
+		WithTypes(sources.AuditedUser{}),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+	)
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		obj  sources.AuditedUser
+		want map[string]string
+	}{
+		{
+			name: "valid",
+			obj:  sources.AuditedUser{Stamp: audit.Stamp{CreatedBy: "alice"}, Base: &sources.Base{ID: "u1"}},
+		},
+		{
+			name: "promoted fields",
+			obj:  sources.AuditedUser{Stamp: audit.Stamp{CreatedBy: "u1"}, Base: &sources.Base{ID: "u1"}},
+			want: map[string]string{pkg + "AuditedUser": `self.ID != self.CreatedBy`},
+		},
+		{
+			name: "nil embedded pointer",
+			obj:  sources.AuditedUser{},
+			want: map[string]string{
+				pkg + "AuditedUser": `self.ID != self.CreatedBy`,
+				"CreatedBy":         `self != ""`,
+				"ID":                `self != null`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(context.Background(), tt.obj)
+			got := ToErrorMap(err)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() errors = %v, want %v\nerr: %v", got, tt.want, err)
+			}
+		})
+	}
+}
+
+type anonContact struct {
+	Name    string
+	Address struct {
+		Zip  string
+		Geo  *struct{ Lat float64 }
+		Note string
+	}
+	Phones []struct {
+		Number string
+		Backup string
+	}
+}
+
+func TestValidator_Validate_AnonymousStructs(t *testing.T) {
+	const key = "github.com/podhmo/veritas.anonContact"
+	rules := map[string]ValidationRuleSet{
+		key + ".Address": {
+			FieldRules: map[string][]string{"Zip": {`self != ""`}},
+		},
+		key + ".Address.Geo": {
+			FieldRules: map[string][]string{"Lat": {`self >= -90.0 && self <= 90.0`}},
+		},
+		key + ".Phones": {
+			FieldRules:      map[string][]string{"Number": {`self != ""`}},
+			CrossFieldRules: map[string][]string{"Backup": {`self.Backup != self.Number`}},
+		},
+	}
+
+	for _, native := range []bool{false, true} {
+		t.Run(fmt.Sprintf("native=%v", native), func(t *testing.T) {
+			opts := []ValidatorOption{
+				WithRuleProvider(&mapRuleProvider{rules: rules}),
+				WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+			}
+			if native {
+				opts = append(opts, WithTypes(anonContact{}))
+			}
+			v, err := NewValidator(opts...)
+			if err != nil {
+				t.Fatalf("NewValidator() failed: %v", err)
+			}
+
+			var valid anonContact
+			valid.Address.Zip = "100-0001"
+			valid.Address.Geo = &struct{ Lat float64 }{Lat: 35.6}
+			valid.Phones = append(valid.Phones, struct {
+				Number string
+				Backup string
+			}{Number: "1", Backup: "2"})
+			if err := v.Validate(context.Background(), valid); err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+
+			var invalid anonContact
+			invalid.Address.Geo = &struct{ Lat float64 }{Lat: 91}
+			invalid.Phones = append(invalid.Phones, struct {
+				Number string
+				Backup string
+			}{Number: "1", Backup: "1"})
+			var got []string
+			for _, ve := range ValidationErrors(v.Validate(context.Background(), &invalid)) {
+				got = append(got, strings.TrimPrefix(ve.TypeName, key)+"."+ve.FieldName+": "+ve.Rule)
+			}
+			sort.Strings(got)
+			want := []string{
+				".Address.Geo.Lat: self >= -90.0 && self <= 90.0",
+				".Address.Zip: self != \"\"",
+				".Phones.Backup: self.Backup != self.Number",
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Validate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidator_Validate_Paths(t *testing.T) {
+	const key = "github.com/podhmo/veritas.anonContact"
+	rules := map[string]ValidationRuleSet{
+		key: {
+			TypeRules:  []string{`self.Name != ""`},
+			FieldRules: map[string][]string{"Name": {`self != ""`}},
+		},
+		key + ".Address": {
+			FieldRules: map[string][]string{"Zip": {`self != ""`}},
+		},
+		key + ".Address.Geo": {
+			FieldRules: map[string][]string{"Lat": {`self >= -90.0 && self <= 90.0`}},
+		},
+		key + ".Phones": {
+			FieldRules:      map[string][]string{"Number": {`self != ""`}},
+			CrossFieldRules: map[string][]string{"Backup": {`self.Backup != self.Number`}},
+		},
+	}
+	v, err := NewValidator(
+		WithRuleProvider(&mapRuleProvider{rules: rules}),
+		WithTypes(anonContact{}),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+	)
+	if err != nil {
+		t.Fatalf("NewValidator() failed: %v", err)
+	}
+
+	var invalid anonContact
+	invalid.Address.Zip = "100-0001"
+	invalid.Address.Geo = &struct{ Lat float64 }{Lat: 91}
+	for _, backup := range []string{"2", "3"} {
+		invalid.Phones = append(invalid.Phones, struct {
+			Number string
+			Backup string
+		}{Number: "3", Backup: backup})
+	}
+	var got []string
+	for _, ve := range ValidationErrors(v.Validate(context.Background(), &invalid)) {
+		got = append(got, fmt.Sprintf("%q: %s", ve.Path, ve.Rule))
+	}
+	sort.Strings(got)
+	want := []string{
+		`"": self.Name != ""`,
+		`"Address.Geo.Lat": self >= -90.0 && self <= 90.0`,
+		`"Name": self != ""`,
+		`"Phones[1].Backup": self.Backup != self.Number`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Validate() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidator_Validate_GenericInstantiations(t *testing.T) {
+	const pkg = "github.com/podhmo/veritas/testdata/sources."
+	itemBox := pkg + "Box[*" + pkg + "Item]"
+	if got := reflect.TypeOf(sources.Box[*sources.Item]{}).PkgPath() + "." + reflect.TypeOf(sources.Box[*sources.Item]{}).Name(); got != itemBox {
+		t.Fatalf("instantiation name = %q, want %q", got, itemBox)
+	}
+	rules := map[string]ValidationRuleSet{
+		pkg + "Box[T]": {
+			FieldRules: map[string][]string{"Value": {`self != null`}},
+		},
+		itemBox: {
+			TypeRules:  []string{`self.Value.Name.size() <= 3`},
+			FieldRules: map[string][]string{"Value": {`self != null`}},
+		},
+	}
+	newValidator := func(types ...any) (*Validator, error) {
+		return NewValidator(
+			WithRuleProvider(&mapRuleProvider{rules: rules}),
+			WithTypes(types...),
+			WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+		)
+	}
+
+	t.Run("instantiation rules are preferred", func(t *testing.T) {
+		v, err := newValidator(sources.Box[*sources.Item]{}, sources.Box[*string]{}, sources.Item{})
+		if err != nil {
+			t.Fatalf("NewValidator() failed: %v", err)
+		}
+		got := ToErrorMap(v.Validate(context.Background(), sources.Box[*sources.Item]{Value: &sources.Item{Name: "long"}}))
+		if want := map[string]string{itemBox: `self.Value.Name.size() <= 3`}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Validate() errors = %v, want %v", got, want)
+		}
+		// Other instantiations fall back to the generic rules.
+		got = ToErrorMap(v.Validate(context.Background(), sources.Box[*string]{}))
+		if want := map[string]string{"Value": `self != null`}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Validate() errors = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("rules are type-checked per instantiation", func(t *testing.T) {
+		rules[pkg+"Box[T]"] = ValidationRuleSet{TypeRules: []string{`self.Value != null`}}
+		defer func() {
+			rules[pkg+"Box[T]"] = ValidationRuleSet{FieldRules: map[string][]string{"Value": {`self != null`}}}
+		}()
+
+		if _, err := newValidator(sources.Box[*sources.Item]{}); err != nil {
+			t.Errorf("NewValidator() failed: %v", err)
+		}
+		_, err := newValidator(sources.Box[string]{})
+		if err == nil || !strings.Contains(err.Error(), `type rule "self.Value != null" of `+pkg+`Box[T] does not compile for sources.Box[string]`) {
+			t.Errorf("NewValidator() error = %v, want a type-check error", err)
+		}
+	})
+}